@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/quentinsteinke/mkvmender/internal/api"
+	"github.com/quentinsteinke/mkvmender/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func newExportCmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Download everything the server holds about you",
+		Long: `Download your profile, API keys (without secrets), submissions with their
+metadata, votes and reports as JSON. By default the archive is written to
+mkvmender-<username>.json in the current directory; use -o - for stdout.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			export, err := client.ExportAccount()
+			if err != nil {
+				return fmt.Errorf("export failed: %w", err)
+			}
+
+			data, err := json.MarshalIndent(export, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode export: %w", err)
+			}
+			data = append(data, '\n')
+
+			if output == "-" {
+				_, err := os.Stdout.Write(data)
+				return err
+			}
+			if output == "" {
+				output = fmt.Sprintf("mkvmender-%s.json", export.User.Username)
+			}
+			if err := os.WriteFile(output, data, 0600); err != nil {
+				return fmt.Errorf("failed to write export: %w", err)
+			}
+
+			fmt.Printf("✓ Exported %d submissions, %d votes and %d reports to %s\n",
+				len(export.Submissions), len(export.Votes), len(export.Reports), output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "File to write (- for stdout)")
+
+	return cmd
+}
+
+func newDeleteAccountCmd() *cobra.Command {
+	var confirm string
+
+	cmd := &cobra.Command{
+		Use:   "delete-account",
+		Short: "Delete your account",
+		Long: `Delete your account, API keys and votes. Your reports stay with
+moderators under a shared "deleted" account. Depending on the server's
+policy your submissions are either kept under that account or deleted too. This cannot be undone; run 'mkvmender export' first
+to keep a copy of your data.
+
+You are asked to type your username to confirm, unless --confirm gives it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if confirm == "" {
+				reader := bufio.NewReader(os.Stdin)
+				fmt.Print("This permanently deletes your account. Type your username to confirm: ")
+				input, _ := reader.ReadString('\n')
+				confirm = strings.TrimSpace(input)
+				if confirm == "" {
+					fmt.Println("Cancelled.")
+					return nil
+				}
+			}
+
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			resp, err := client.DeleteAccount(confirm)
+			if err != nil {
+				return fmt.Errorf("failed to delete account: %w", err)
+			}
+
+			fmt.Println("✓ Account deleted")
+			if resp.Submissions > 0 {
+				if resp.Policy == models.DeletionDelete {
+					fmt.Printf("Your %d submissions were deleted.\n", resp.Submissions)
+				} else {
+					fmt.Printf("Your %d submissions were kept and are now credited to \"deleted\".\n", resp.Submissions)
+				}
+			}
+
+			config, err := api.LoadConfig()
+			if err != nil {
+				return nil
+			}
+			config.APIKey = ""
+			if err := api.SaveConfig(config); err != nil {
+				return fmt.Errorf("failed to clear API key from config: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&confirm, "confirm", "", "Your username, to skip the confirmation prompt")
+
+	return cmd
+}
@@ -0,0 +1,560 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/quentinsteinke/mkvmender/internal/api"
+	"github.com/quentinsteinke/mkvmender/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func newAdminCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "admin",
+		Short: "Moderate users and submissions (staff only)",
+		Long: `Moderate users and submissions and read the moderation log.
+
+These commands need a moderator or admin account and an API key with the
+admin scope (mkvmender keys create <name> --scopes read,admin). What each
+role may do is listed in the server documentation.`,
+	}
+
+	cmd.AddCommand(newAdminUsersCmd())
+	cmd.AddCommand(newAdminSubmissionsCmd())
+	cmd.AddCommand(newAdminSuspendCmd())
+	cmd.AddCommand(newAdminActivateCmd())
+	cmd.AddCommand(newAdminRoleCmd())
+	cmd.AddCommand(newAdminDeleteCmd())
+	cmd.AddCommand(newAdminKeysCmd())
+	cmd.AddCommand(newAdminRevokeKeyCmd())
+	cmd.AddCommand(newAdminLogCmd())
+	cmd.AddCommand(newAdminTimelineCmd())
+
+	return cmd
+}
+
+func newAdminUsersCmd() *cobra.Command {
+	var (
+		role   string
+		status string
+		page   int
+		limit  int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "users",
+		Short: "List users",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			users, total, err := client.AdminListUsers(role, status, page, limit)
+			if err != nil {
+				return fmt.Errorf("failed to list users: %w", err)
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tUSERNAME\tROLE\tSTATUS\tSUBMISSIONS\tJOINED\t")
+			for _, user := range users {
+				status := "active"
+				if !user.IsActive {
+					status = "suspended"
+					if user.SuspendedUntil != nil {
+						status = "suspended until " + user.SuspendedUntil.Local().Format("2006-01-02 15:04")
+					}
+				}
+				fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d\t%s\t\n",
+					user.ID, user.Username, user.Role, status, user.SubmissionCount,
+					user.CreatedAt.Local().Format("2006-01-02"))
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+
+			printPage(page, limit, total)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&role, "role", "", "Only users with this role (user, moderator, admin)")
+	cmd.Flags().StringVar(&status, "status", "", "Only active or suspended users")
+	addPageFlags(cmd, &page, &limit)
+
+	return cmd
+}
+
+func newAdminSubmissionsCmd() *cobra.Command {
+	var (
+		userID  int64
+		sortBy  string
+		deleted bool
+		page    int
+		limit   int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "submissions",
+		Short: "List naming submissions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			submissions, total, err := client.AdminListSubmissions(userID, sortBy, deleted, page, limit)
+			if err != nil {
+				return fmt.Errorf("failed to list submissions: %w", err)
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tFILENAME\tUSER\tSCORE\tSTATE\tCREATED\t")
+			for _, s := range submissions {
+				var state []string
+				if s.Pending {
+					state = append(state, "pending")
+				}
+				if s.Hidden {
+					state = append(state, "hidden")
+				}
+				if s.DeletedAt != nil {
+					state = append(state, "deleted "+s.DeletedAt.Local().Format("2006-01-02"))
+				}
+				if len(state) == 0 {
+					state = append(state, "live")
+				}
+				fmt.Fprintf(tw, "%d\t%s\t%s\t%+d\t%s\t%s\t\n",
+					s.ID, s.Filename, s.Username, s.VoteScore, strings.Join(state, ", "),
+					s.CreatedAt.Local().Format("2006-01-02"))
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+
+			printPage(page, limit, total)
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64Var(&userID, "user", 0, "Only submissions by this user ID")
+	cmd.Flags().StringVar(&sortBy, "sort", "", "Sort by date, votes or title")
+	cmd.Flags().BoolVar(&deleted, "deleted", false, "List deleted submissions instead")
+	addPageFlags(cmd, &page, &limit)
+
+	return cmd
+}
+
+func newAdminSuspendCmd() *cobra.Command {
+	var (
+		duration string
+		reason   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "suspend <user-id>",
+		Short: "Suspend a user",
+		Long: `Suspend a user, permanently unless --duration is given. Moderators can only
+suspend regular users.`,
+		Example: `  mkvmender admin suspend 42 --duration 7d --reason "spam uploads"`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := &models.ChangeStatusRequest{IsActive: false, Duration: duration}
+			if reason != "" {
+				req.Reason = &reason
+			}
+			return setUserStatus(args[0], req)
+		},
+	}
+
+	cmd.Flags().StringVar(&duration, "duration", "", "How long to suspend for (e.g. 72h or 7d)")
+	cmd.Flags().StringVar(&reason, "reason", "", "Reason recorded in the moderation log")
+
+	return cmd
+}
+
+func newAdminActivateCmd() *cobra.Command {
+	var reason string
+
+	cmd := &cobra.Command{
+		Use:   "activate <user-id>",
+		Short: "Lift a user's suspension",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := &models.ChangeStatusRequest{IsActive: true}
+			if reason != "" {
+				req.Reason = &reason
+			}
+			return setUserStatus(args[0], req)
+		},
+	}
+
+	cmd.Flags().StringVar(&reason, "reason", "", "Reason recorded in the moderation log")
+
+	return cmd
+}
+
+// setUserStatus sends a status change for the user ID in arg and prints the
+// server's confirmation
+func setUserStatus(arg string, req *models.ChangeStatusRequest) error {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %s", arg)
+	}
+
+	client, err := api.NewClient()
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	message, err := client.AdminSetUserStatus(id, req)
+	if err != nil {
+		return fmt.Errorf("failed to change user status: %w", err)
+	}
+
+	fmt.Printf("✓ User %d: %s\n", id, message)
+	return nil
+}
+
+func newAdminRoleCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "role <user-id> <user|moderator|admin>",
+		Short: "Change a user's role (admins only)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid user ID: %s", args[0])
+			}
+
+			role := models.UserRole(args[1])
+			switch role {
+			case models.RoleUser, models.RoleModerator, models.RoleAdmin:
+			default:
+				return fmt.Errorf("invalid role %q: must be user, moderator or admin", args[1])
+			}
+
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			if err := client.AdminChangeRole(id, role); err != nil {
+				return fmt.Errorf("failed to change role: %w", err)
+			}
+
+			fmt.Printf("✓ User %d is now %s\n", id, role)
+			return nil
+		},
+	}
+}
+
+func newAdminDeleteCmd() *cobra.Command {
+	var (
+		reason string
+		yes    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "delete <submission-id>",
+		Short: "Delete a naming submission",
+		Long: `Delete a naming submission. It can be restored by an admin until the
+server's retention period passes.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid submission ID: %s", args[0])
+			}
+
+			if !yes {
+				reader := bufio.NewReader(os.Stdin)
+				fmt.Printf("Delete submission %d? (y/n): ", id)
+				input, _ := reader.ReadString('\n')
+				input = strings.TrimSpace(strings.ToLower(input))
+				if input != "y" && input != "yes" {
+					fmt.Println("Cancelled.")
+					return nil
+				}
+			}
+
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			if err := client.AdminDeleteSubmission(id, reason); err != nil {
+				return fmt.Errorf("delete failed: %w", err)
+			}
+
+			fmt.Printf("✓ Deleted submission %d\n", id)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&reason, "reason", "", "Reason recorded in the moderation log")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation")
+
+	return cmd
+}
+
+func newAdminKeysCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "keys <user-id>",
+		Short: "List a user's API keys (admins only)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid user ID: %s", args[0])
+			}
+
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			keys, err := client.AdminListKeys(id)
+			if err != nil {
+				return fmt.Errorf("failed to list keys: %w", err)
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tNAME\tPREFIX\tSCOPES\tCREATED\tLAST USED")
+			for _, key := range keys {
+				lastUsed := "never"
+				if key.LastUsedAt != nil {
+					lastUsed = key.LastUsedAt.Local().Format("2006-01-02 15:04")
+				}
+				fmt.Fprintf(tw, "%d\t%s\t%s…\t%s\t%s\t%s\n",
+					key.ID, key.Name, key.Prefix, formatScopes(key.Scopes),
+					key.CreatedAt.Local().Format("2006-01-02 15:04"), lastUsed)
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+func newAdminRevokeKeyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke-key <key-id>",
+		Short: "Revoke any user's API key (admins only)",
+		Long: `Revoke any user's API key, such as a leaked one. Unlike 'mkvmender keys
+revoke', this also revokes a user's last key, which locks them out.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid key ID: %s", args[0])
+			}
+
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			if err := client.AdminRevokeKey(id); err != nil {
+				return fmt.Errorf("failed to revoke key: %w", err)
+			}
+
+			fmt.Printf("✓ Revoked key %d\n", id)
+			return nil
+		},
+	}
+}
+
+func newAdminLogCmd() *cobra.Command {
+	var (
+		adminID    int64
+		actionType string
+		targetType string
+		targetID   int64
+		since      string
+		until      string
+		page       int
+		limit      int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "log",
+		Short: "Show the moderation log",
+		Long: `Show logged moderation actions, newest first.
+
+--since and --until take a date (2024-05-01), an RFC 3339 time or a
+duration before now (24h, 7d).`,
+		Example: `  mkvmender admin log --since 7d
+  mkvmender admin log --admin 3 --action suspend_user
+  mkvmender admin log --target-type submission --target 42`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			params := url.Values{}
+			params.Add("page", strconv.Itoa(page))
+			params.Add("limit", strconv.Itoa(limit))
+			if adminID != 0 {
+				params.Add("admin_id", strconv.FormatInt(adminID, 10))
+			}
+			if actionType != "" {
+				params.Add("action_type", actionType)
+			}
+			if targetType != "" {
+				params.Add("target_type", targetType)
+			}
+			if targetID != 0 {
+				params.Add("target_id", strconv.FormatInt(targetID, 10))
+			}
+			for name, value := range map[string]string{"since": since, "until": until} {
+				if value == "" {
+					continue
+				}
+				t, err := parseTimeFlag(value, time.Now())
+				if err != nil {
+					return fmt.Errorf("invalid --%s: %w", name, err)
+				}
+				params.Add(name, t.UTC().Format(time.RFC3339))
+			}
+
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			actions, total, err := client.AdminListActions(params)
+			if err != nil {
+				return fmt.Errorf("failed to fetch moderation log: %w", err)
+			}
+
+			if err := printActions(actions, true); err != nil {
+				return err
+			}
+
+			printPage(page, limit, total)
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64Var(&adminID, "admin", 0, "Only actions by this staff user ID")
+	cmd.Flags().StringVar(&actionType, "action", "", "Only this action type (e.g. delete_submission)")
+	cmd.Flags().StringVar(&targetType, "target-type", "", "Only actions on user, submission, invite or vote_flag targets")
+	cmd.Flags().Int64Var(&targetID, "target", 0, "Only actions on this target ID")
+	cmd.Flags().StringVar(&since, "since", "", "Only actions at or after this time")
+	cmd.Flags().StringVar(&until, "until", "", "Only actions before this time")
+	addPageFlags(cmd, &page, &limit)
+
+	return cmd
+}
+
+func newAdminTimelineCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "timeline <user|submission|invite|vote_flag> <id>",
+		Short:   "Show every moderation action on one target",
+		Example: `  mkvmender admin timeline submission 42`,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid target ID: %s", args[1])
+			}
+
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			actions, err := client.AdminTargetTimeline(args[0], id)
+			if err != nil {
+				return fmt.Errorf("failed to fetch timeline: %w", err)
+			}
+
+			if len(actions) == 0 {
+				fmt.Printf("No moderation actions on %s %d.\n", args[0], id)
+				return nil
+			}
+
+			return printActions(actions, false)
+		},
+	}
+}
+
+// printActions prints moderation log entries as a table, with the target
+// column when they are not all for the same target
+func printActions(actions []models.ModerationLogEntry, showTarget bool) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if showTarget {
+		fmt.Fprintln(tw, "ID\tTIME\tBY\tACTION\tTARGET\tREASON\t")
+	} else {
+		fmt.Fprintln(tw, "ID\tTIME\tBY\tACTION\tREASON\t")
+	}
+
+	for _, action := range actions {
+		reason := ""
+		if action.Reason != nil {
+			reason = *action.Reason
+		}
+		if action.ExpiresAt != nil {
+			reason = strings.TrimSpace(reason + " (until " + action.ExpiresAt.Local().Format("2006-01-02 15:04") + ")")
+		}
+
+		by := action.AdminUsername
+		if by == "" {
+			by = fmt.Sprintf("#%d", action.AdminID)
+		}
+
+		created := action.CreatedAt.Local().Format("2006-01-02 15:04")
+		if showTarget {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s %d\t%s\t\n",
+				action.ID, created, by, action.ActionType, action.TargetType, action.TargetID, reason)
+		} else {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t\n",
+				action.ID, created, by, action.ActionType, reason)
+		}
+	}
+
+	return tw.Flush()
+}
+
+// addPageFlags adds --page and --limit flags for paginated admin listings
+func addPageFlags(cmd *cobra.Command, page, limit *int) {
+	cmd.Flags().IntVar(page, "page", 1, "Page number")
+	cmd.Flags().IntVar(limit, "limit", 50, "Results per page (at most 100)")
+}
+
+// printPage prints which page of a paginated listing was shown
+func printPage(page, limit, total int) {
+	pages := (total + limit - 1) / limit
+	if pages < 1 {
+		pages = 1
+	}
+	fmt.Printf("\nPage %d of %d (%d total)\n", page, pages, total)
+}
+
+// parseTimeFlag parses a date (2006-01-02), an RFC 3339 time or a duration
+// before now such as "24h" or "7d"
+func parseTimeFlag(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err == nil {
+			return now.Add(-time.Duration(n) * 24 * time.Hour), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("%q is not a date, RFC 3339 time or duration", value)
+}
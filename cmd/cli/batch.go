@@ -13,13 +13,19 @@ import (
 
 func newBatchCmd() *cobra.Command {
 	var dryRun bool
+	var apply bool
+	var minConfidence float64
 	var extensions []string
 
 	cmd := &cobra.Command{
 		Use:   "batch <directory>",
 		Short: "Process all media files in a directory",
-		Long:  "Recursively process all media files in a directory and look up naming options.",
-		Args:  cobra.ExactArgs(1),
+		Long: `Recursively process all media files in a directory and look up naming options.
+
+With --apply, each file is renamed to its best match, as ranked by the
+server, when that match's confidence is at least --min-confidence. Files
+without a confident match are left alone; use 'mkvmender rename' for those.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			directory := args[0]
 
@@ -82,8 +88,8 @@ func newBatchCmd() *cobra.Command {
 					continue
 				}
 
-				// Lookup
-				response, err := client.Lookup(result.Hash)
+				// Lookup, ranked by the server's default
+				response, err := client.Lookup(result.Hash, "")
 				if err != nil {
 					fmt.Printf("  Error looking up: %v\n\n", err)
 					continue
@@ -96,10 +102,20 @@ func newBatchCmd() *cobra.Command {
 
 				// Show top result
 				top := response.Submissions[0]
-				fmt.Printf("  Best match: %s (votes: %d)\n", top.Filename, top.VoteScore)
+				fmt.Printf("  Best match: %s (votes: %d, confidence: %.0f%%)\n",
+					top.Filename, top.VoteScore, top.Confidence*100)
 
-				if !dryRun {
-					fmt.Printf("  (Use 'mkvmender rename' to apply)\n")
+				switch {
+				case !apply:
+					if !dryRun {
+						fmt.Printf("  (Use 'mkvmender rename' or --apply to apply)\n")
+					}
+				case top.Upvotes+top.Downvotes == 0:
+					fmt.Printf("  Skipped: no votes yet\n")
+				case top.Confidence < minConfidence:
+					fmt.Printf("  Skipped: confidence below %.0f%%\n", minConfidence*100)
+				default:
+					applyBatchRename(file, top.Filename, dryRun)
 				}
 				fmt.Println()
 			}
@@ -109,7 +125,37 @@ func newBatchCmd() *cobra.Command {
 	}
 
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Preview without making changes")
+	cmd.Flags().BoolVar(&apply, "apply", false, "Rename files to their best match when it is confident enough")
+	cmd.Flags().Float64Var(&minConfidence, "min-confidence", 0.5, "Minimum confidence (0-1) for --apply to rename a file")
 	cmd.Flags().StringSliceVarP(&extensions, "ext", "e", nil, "File extensions to process (default: .mkv,.mp4,.avi,.m4v)")
 
 	return cmd
 }
+
+// applyBatchRename renames file to a submitted filename, refusing to
+// overwrite an existing file
+func applyBatchRename(file, filename string, dryRun bool) {
+	newPath, err := renameTarget(file, filename)
+	if err != nil {
+		fmt.Printf("  Skipped: %v\n", err)
+		return
+	}
+	if newPath == file {
+		fmt.Printf("  Already named correctly\n")
+		return
+	}
+	if _, err := os.Stat(newPath); err == nil {
+		fmt.Printf("  Skipped: %s already exists\n", filepath.Base(newPath))
+		return
+	}
+
+	if dryRun {
+		fmt.Printf("  [DRY RUN] Would rename to: %s\n", filepath.Base(newPath))
+		return
+	}
+	if err := os.Rename(file, newPath); err != nil {
+		fmt.Printf("  Error renaming file: %v\n", err)
+		return
+	}
+	fmt.Printf("  Renamed to: %s\n", filepath.Base(newPath))
+}
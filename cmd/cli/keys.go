@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/quentinsteinke/mkvmender/internal/api"
+	"github.com/quentinsteinke/mkvmender/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func newKeysCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage your API keys",
+		Long: `List, create, rotate and revoke the named API keys on your account.
+
+Use separate keys per machine (e.g. "laptop", "nas") so one can be revoked
+without affecting the others.`,
+	}
+
+	cmd.AddCommand(newKeysListCmd())
+	cmd.AddCommand(newKeysCreateCmd())
+	cmd.AddCommand(newKeysRotateCmd())
+	cmd.AddCommand(newKeysRevokeCmd())
+
+	return cmd
+}
+
+func newKeysListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List your API keys",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			keys, err := client.ListKeys()
+			if err != nil {
+				return fmt.Errorf("failed to list keys: %w", err)
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tNAME\tPREFIX\tSCOPES\tCREATED\tLAST USED\t")
+			for _, key := range keys {
+				lastUsed := "never"
+				if key.LastUsedAt != nil {
+					lastUsed = key.LastUsedAt.Local().Format("2006-01-02 15:04")
+				}
+				current := ""
+				if key.Current {
+					current = "(this key)"
+				}
+				fmt.Fprintf(tw, "%d\t%s\t%s…\t%s\t%s\t%s\t%s\n",
+					key.ID, key.Name, key.Prefix, formatScopes(key.Scopes),
+					key.CreatedAt.Local().Format("2006-01-02 15:04"), lastUsed, current)
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+func newKeysCreateCmd() *cobra.Command {
+	var save bool
+	var scopes []string
+
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a new named API key",
+		Long: `Create a new named API key. Scopes limit what the key can do:
+
+  read    read account data (verify, key list)
+  upload  upload naming submissions
+  vote    cast and remove votes
+  admin   use admin endpoints (admin and moderator accounts only)
+
+Without --scopes the key gets read, upload and vote. A key can only create
+keys with scopes it holds itself.`,
+		Example: `  mkvmender keys create nas --scopes read
+  mkvmender keys create laptop --save`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			var keyScopes []models.Scope
+			for _, scope := range scopes {
+				keyScopes = append(keyScopes, models.Scope(strings.TrimSpace(scope)))
+			}
+
+			key, err := client.CreateKey(args[0], keyScopes)
+			if err != nil {
+				return fmt.Errorf("failed to create key: %w", err)
+			}
+
+			fmt.Printf("Created key %q (ID %d) with scopes: %s\n", key.Name, key.ID, formatScopes(key.Scopes))
+			fmt.Printf("API Key: %s\n", key.Key)
+			fmt.Println("\nIMPORTANT: This is the only time the key is shown. Save it securely.")
+
+			if save {
+				if err := saveAPIKey(key.Key); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&save, "save", false, "Use the new key in the local config file")
+	cmd.Flags().StringSliceVar(&scopes, "scopes", nil, "Comma-separated scopes (read, upload, vote, admin)")
+
+	return cmd
+}
+
+func newKeysRotateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rotate <id>",
+		Short: "Replace the secret of an API key",
+		Long: `Replace the secret of an API key, keeping its name. The old secret stops
+working immediately. Rotating the key this CLI uses updates the config file.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid key ID: %s", args[0])
+			}
+
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			key, err := client.RotateKey(id)
+			if err != nil {
+				return fmt.Errorf("failed to rotate key: %w", err)
+			}
+
+			fmt.Printf("Rotated key %q (ID %d)\n", key.Name, key.ID)
+			if key.Current {
+				return saveAPIKey(key.Key)
+			}
+
+			fmt.Printf("API Key: %s\n", key.Key)
+			fmt.Println("\nIMPORTANT: This is the only time the key is shown. Save it securely.")
+			return nil
+		},
+	}
+}
+
+func newKeysRevokeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke <id>",
+		Short: "Revoke an API key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid key ID: %s", args[0])
+			}
+
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			if err := client.RevokeKey(id); err != nil {
+				return fmt.Errorf("failed to revoke key: %w", err)
+			}
+
+			fmt.Printf("✓ Revoked key %d\n", id)
+			return nil
+		},
+	}
+}
+
+// formatScopes renders scopes as a comma-separated list
+func formatScopes(scopes []models.Scope) string {
+	parts := make([]string, len(scopes))
+	for i, scope := range scopes {
+		parts[i] = string(scope)
+	}
+	return strings.Join(parts, ",")
+}
+
+// saveAPIKey stores apiKey in the local config file
+func saveAPIKey(apiKey string) error {
+	config, err := api.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	config.APIKey = apiKey
+	if err := api.SaveConfig(config); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	configPath, _ := api.ConfigPath()
+	fmt.Printf("Configuration saved to: %s\n", configPath)
+	return nil
+}
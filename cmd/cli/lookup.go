@@ -9,6 +9,8 @@ import (
 )
 
 func newLookupCmd() *cobra.Command {
+	var rank string
+
 	cmd := &cobra.Command{
 		Use:   "lookup <file>",
 		Short: "Look up naming options for a media file",
@@ -35,7 +37,7 @@ func newLookupCmd() *cobra.Command {
 
 			// Lookup naming options
 			fmt.Println("Looking up naming options...")
-			response, err := client.Lookup(result.Hash)
+			response, err := client.Lookup(result.Hash, rank)
 			if err != nil {
 				return fmt.Errorf("lookup failed: %w", err)
 			}
@@ -49,8 +51,10 @@ func newLookupCmd() *cobra.Command {
 			fmt.Printf("Found %d naming option(s):\n\n", len(response.Submissions))
 			for i, submission := range response.Submissions {
 				fmt.Printf("[%d] %s\n", i+1, submission.Filename)
-				fmt.Printf("    Submitted by: %s\n", submission.Username)
+				fmt.Printf("    ID: %d\n", submission.ID)
+				fmt.Printf("    Submitted by: %s (reputation %d, %s)\n", submission.Username, submission.UserReputation, submission.UserTrustLevel)
 				fmt.Printf("    Votes: %d (↑%d ↓%d)\n", submission.VoteScore, submission.Upvotes, submission.Downvotes)
+				fmt.Printf("    Confidence: %.0f%%\n", submission.Confidence*100)
 				fmt.Printf("    Media Type: %s\n", submission.MediaType)
 
 				if submission.Metadata != nil {
@@ -74,5 +78,7 @@ func newLookupCmd() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().StringVar(&rank, "rank", "", "Rank submissions by: wilson, bayesian, decay (default: server's choice)")
+
 	return cmd
 }
@@ -31,11 +31,19 @@ Use 'mkvmender login' to configure your API key.`,
 	rootCmd.AddCommand(newLookupCmd())
 	rootCmd.AddCommand(newRenameCmd())
 	rootCmd.AddCommand(newUploadCmd())
+	rootCmd.AddCommand(newMySubmissionsCmd())
+	rootCmd.AddCommand(newEditCmd())
+	rootCmd.AddCommand(newRetractCmd())
+	rootCmd.AddCommand(newReportCmd())
 	rootCmd.AddCommand(newVoteCmd())
 	rootCmd.AddCommand(newBatchCmd())
 	rootCmd.AddCommand(newSearchCmd())
 	rootCmd.AddCommand(newLoginCmd())
 	rootCmd.AddCommand(newRegisterCmd())
+	rootCmd.AddCommand(newKeysCmd())
+	rootCmd.AddCommand(newExportCmd())
+	rootCmd.AddCommand(newDeleteAccountCmd())
+	rootCmd.AddCommand(newAdminCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -7,17 +7,26 @@ import (
 	"strings"
 
 	"github.com/quentinsteinke/mkvmender/internal/api"
+	"github.com/quentinsteinke/mkvmender/internal/models"
 	"github.com/spf13/cobra"
 )
 
 func newRegisterCmd() *cobra.Command {
 	var username string
 	var baseURL string
+	var inviteCode string
 
 	cmd := &cobra.Command{
 		Use:   "register",
 		Short: "Register a new user account",
-		Long:  "Create a new user account and receive an API key.",
+		Long: `Create a new user account and receive an API key.
+
+Usernames are 3-32 letters or digits, optionally separated by single _ - or .
+characters. Names that differ only in case, separators or look-alike
+characters count as the same name.
+
+Some servers require an invite code (--invite) or make the CLI solve a short
+proof-of-work challenge, which happens automatically.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Load existing config for base URL
 			config, err := api.LoadConfig()
@@ -46,7 +55,13 @@ func newRegisterCmd() *cobra.Command {
 
 			// Register user
 			fmt.Println("Registering user...")
-			user, err := client.Register(username)
+			user, err := client.Register(&models.RegisterRequest{
+				Username:   username,
+				InviteCode: inviteCode,
+			})
+			if api.IsErrorCode(err, models.ErrorCodeInviteRequired) {
+				return fmt.Errorf("this server requires an invite code; ask an admin for one and pass it with --invite")
+			}
 			if err != nil {
 				return fmt.Errorf("registration failed: %w", err)
 			}
@@ -78,6 +93,7 @@ func newRegisterCmd() *cobra.Command {
 
 	cmd.Flags().StringVarP(&username, "username", "u", "", "Username for registration")
 	cmd.Flags().StringVar(&baseURL, "url", "", "Base URL for the API server")
+	cmd.Flags().StringVar(&inviteCode, "invite", "", "Invite code, if the server requires one")
 
 	return cmd
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/quentinsteinke/mkvmender/internal/api"
 	"github.com/quentinsteinke/mkvmender/internal/hasher"
+	"github.com/quentinsteinke/mkvmender/internal/naming"
 	"github.com/spf13/cobra"
 )
 
@@ -39,7 +40,7 @@ func newRenameCmd() *cobra.Command {
 
 			// Lookup naming options
 			fmt.Println("Looking up naming options...")
-			response, err := client.Lookup(result.Hash)
+			response, err := client.Lookup(result.Hash, "")
 			if err != nil {
 				return fmt.Errorf("lookup failed: %w", err)
 			}
@@ -73,17 +74,11 @@ func newRenameCmd() *cobra.Command {
 
 			selectedSubmission := response.Submissions[selection-1]
 
-			// Get directory and new path
-			dir := filepath.Dir(filePath)
-			ext := filepath.Ext(filePath)
-			newFilename := selectedSubmission.Filename
-
-			// Ensure the new filename has the same extension
-			if !strings.HasSuffix(newFilename, ext) {
-				newFilename = strings.TrimSuffix(newFilename, filepath.Ext(newFilename)) + ext
+			newPath, err := renameTarget(filePath, selectedSubmission.Filename)
+			if err != nil {
+				return err
 			}
-
-			newPath := filepath.Join(dir, newFilename)
+			newFilename := filepath.Base(newPath)
 
 			// Preview rename
 			fmt.Printf("\nRename:\n")
@@ -119,3 +114,18 @@ func newRenameCmd() *cobra.Command {
 
 	return cmd
 }
+
+// renameTarget returns the path filePath would be renamed to for a
+// submitted filename, keeping the file's directory and extension. Names that
+// would move the file out of its directory are refused.
+func renameTarget(filePath, filename string) (string, error) {
+	if err := naming.CheckFilename(filename); err != nil || filepath.Base(filename) != filename {
+		return "", fmt.Errorf("refusing unsafe filename %q", filename)
+	}
+
+	ext := filepath.Ext(filePath)
+	if !strings.HasSuffix(filename, ext) {
+		filename = strings.TrimSuffix(filename, filepath.Ext(filename)) + ext
+	}
+	return filepath.Join(filepath.Dir(filePath), filename), nil
+}
@@ -17,6 +17,7 @@ import (
 func newSearchCmd() *cobra.Command {
 	var sortBy string
 	var noFuzzy bool
+	var rank string
 
 	cmd := &cobra.Command{
 		Use:   "search <title>",
@@ -34,7 +35,7 @@ func newSearchCmd() *cobra.Command {
 
 			// Search
 			fmt.Printf("Searching for '%s'...\n\n", query)
-			response, err := client.Search(query, sortBy, !noFuzzy)
+			response, err := client.Search(query, sortBy, rank, !noFuzzy)
 			if err != nil {
 				return fmt.Errorf("search failed: %w", err)
 			}
@@ -89,6 +90,7 @@ func newSearchCmd() *cobra.Command {
 
 	cmd.Flags().StringVarP(&sortBy, "sort", "s", "relevance", "Sort results by: relevance, votes, date, title")
 	cmd.Flags().BoolVar(&noFuzzy, "no-fuzzy", false, "Disable fuzzy matching (use exact string matching)")
+	cmd.Flags().StringVar(&rank, "rank", "", "Rank submissions by: wilson, bayesian, decay (default: server's choice)")
 
 	return cmd
 }
@@ -167,8 +169,9 @@ func displayMovieSubmissions(group searchGroup) error {
 	fmt.Printf("Found %d naming submission(s):\n\n", len(result.Submissions))
 	for i, submission := range result.Submissions {
 		fmt.Printf("[%d] %s\n", i+1, submission.Filename)
-		fmt.Printf("    Submitted by: %s\n", submission.Username)
+		fmt.Printf("    Submitted by: %s (reputation %d, %s)\n", submission.Username, submission.UserReputation, submission.UserTrustLevel)
 		fmt.Printf("    Votes: %d (↑%d ↓%d)\n", submission.VoteScore, submission.Upvotes, submission.Downvotes)
+		fmt.Printf("    Confidence: %.0f%%\n", submission.Confidence*100)
 		if submission.Metadata != nil {
 			if submission.Metadata.Quality != nil {
 				fmt.Printf("    Quality: %s\n", *submission.Metadata.Quality)
@@ -291,8 +294,9 @@ func displayEpisodeSubmissions(title string, seasonNum int, episode models.Searc
 	fmt.Printf("Found %d naming submission(s):\n\n", len(episode.Submissions))
 	for i, submission := range episode.Submissions {
 		fmt.Printf("[%d] %s\n", i+1, submission.Filename)
-		fmt.Printf("    Submitted by: %s\n", submission.Username)
+		fmt.Printf("    Submitted by: %s (reputation %d, %s)\n", submission.Username, submission.UserReputation, submission.UserTrustLevel)
 		fmt.Printf("    Votes: %d (↑%d ↓%d)\n", submission.VoteScore, submission.Upvotes, submission.Downvotes)
+		fmt.Printf("    Confidence: %.0f%%\n", submission.Confidence*100)
 		if submission.Metadata != nil {
 			if submission.Metadata.Quality != nil {
 				fmt.Printf("    Quality: %s\n", *submission.Metadata.Quality)
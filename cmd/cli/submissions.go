@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/quentinsteinke/mkvmender/internal/api"
+	"github.com/quentinsteinke/mkvmender/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func newMySubmissionsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "my-submissions",
+		Short: "List your naming submissions",
+		Long:  "List the naming submissions you uploaded, with their IDs for 'edit' and 'retract'.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			submissions, err := client.MySubmissions()
+			if err != nil {
+				return fmt.Errorf("failed to list submissions: %w", err)
+			}
+
+			if len(submissions) == 0 {
+				fmt.Println("You have not uploaded any submissions yet.")
+				return nil
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tSCORE\tVOTES\tFILENAME\tHASH\tUPLOADED")
+			for _, s := range submissions {
+				fmt.Fprintf(tw, "%d\t%d\t↑%d ↓%d\t%s\t%s…\t%s\n",
+					s.ID, s.VoteScore, s.Upvotes, s.Downvotes, s.Filename,
+					s.Hash[:min(12, len(s.Hash))], s.CreatedAt.Local().Format("2006-01-02"))
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+func newEditCmd() *cobra.Command {
+	var (
+		filename string
+		title    string
+		year     int
+		season   int
+		episode  int
+		quality  string
+		source   string
+		reason   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "edit <submission-id>",
+		Short: "Edit one of your naming submissions",
+		Long: `Change the filename or metadata of a submission you uploaded. Only the
+flags you pass are changed. Earlier versions are kept in the submission's
+history.
+
+Renaming a submission to a materially different name (more than case,
+spacing or punctuation) may reset its votes, depending on the server.`,
+		Example: `  mkvmender edit 42 --name "The Matrix (1999).mkv" --reason "fix year"
+  mkvmender edit 42 --quality 2160p`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid submission ID: %s", args[0])
+			}
+
+			req := &models.UpdateSubmissionRequest{Filename: filename}
+			if reason != "" {
+				req.Reason = &reason
+			}
+
+			flags := cmd.Flags()
+			if flags.Changed("title") || flags.Changed("year") || flags.Changed("season") ||
+				flags.Changed("episode") || flags.Changed("quality") || flags.Changed("source") {
+				metadata := &models.NamingMetadata{}
+				if flags.Changed("title") {
+					metadata.Title = &title
+				}
+				if flags.Changed("year") {
+					metadata.Year = &year
+				}
+				if flags.Changed("season") {
+					metadata.Season = &season
+				}
+				if flags.Changed("episode") {
+					metadata.Episode = &episode
+				}
+				if flags.Changed("quality") {
+					metadata.Quality = &quality
+				}
+				if flags.Changed("source") {
+					metadata.Source = &source
+				}
+				req.Metadata = metadata
+			}
+
+			if req.Filename == "" && req.Metadata == nil {
+				return fmt.Errorf("nothing to change; pass --name or a metadata flag")
+			}
+
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			response, err := client.UpdateSubmission(id, req)
+			if err != nil {
+				return fmt.Errorf("edit failed: %w", err)
+			}
+
+			fmt.Printf("✓ Updated submission %d: %s\n", id, response.Submission.Filename)
+			if response.VotesReset {
+				fmt.Println("  The filename changed materially, so earlier votes were reset.")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&filename, "name", "n", "", "New filename")
+	cmd.Flags().StringVar(&title, "title", "", "Title of the movie/show")
+	cmd.Flags().IntVar(&year, "year", 0, "Release year")
+	cmd.Flags().IntVar(&season, "season", 0, "Season number (for TV shows)")
+	cmd.Flags().IntVar(&episode, "episode", 0, "Episode number (for TV shows)")
+	cmd.Flags().StringVar(&quality, "quality", "", "Quality (e.g., 1080p, 4K)")
+	cmd.Flags().StringVar(&source, "source", "", "Source (e.g., Blu-ray, DVD)")
+	cmd.Flags().StringVar(&reason, "reason", "", "Why the change was made (shown in the history)")
+
+	return cmd
+}
+
+func newRetractCmd() *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "retract <submission-id>",
+		Short: "Delete one of your naming submissions",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid submission ID: %s", args[0])
+			}
+
+			if !yes {
+				reader := bufio.NewReader(os.Stdin)
+				fmt.Printf("Retract submission %d? Its votes will be lost. (y/n): ", id)
+				input, _ := reader.ReadString('\n')
+				input = strings.TrimSpace(strings.ToLower(input))
+				if input != "y" && input != "yes" {
+					fmt.Println("Cancelled.")
+					return nil
+				}
+			}
+
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			if err := client.RetractSubmission(id); err != nil {
+				return fmt.Errorf("retract failed: %w", err)
+			}
+
+			fmt.Printf("✓ Retracted submission %d\n", id)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation")
+
+	return cmd
+}
+
+func newReportCmd() *cobra.Command {
+	var (
+		reason  string
+		details string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "report <submission-id>",
+		Short: "Report a bad naming submission to moderators",
+		Long: `Report a naming submission as having the wrong title or episode, or as
+spam or offensive. Submission IDs are shown by 'lookup'. A submission
+reported by enough users is hidden until a moderator reviews it.`,
+		Example: `  mkvmender report 42 --reason wrong_episode --details "this is S01E03"`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid submission ID: %s", args[0])
+			}
+
+			req := &models.CreateReportRequest{Reason: models.ReportReason(reason)}
+			if !models.ValidReportReason(req.Reason) {
+				return fmt.Errorf("invalid reason %q (use wrong_title, wrong_episode, spam or offensive)", reason)
+			}
+			if details != "" {
+				req.Details = &details
+			}
+
+			client, err := api.NewClient()
+			if err != nil {
+				return fmt.Errorf("failed to create API client: %w", err)
+			}
+
+			response, err := client.ReportSubmission(id, req)
+			if err != nil {
+				return fmt.Errorf("report failed: %w", err)
+			}
+
+			fmt.Printf("✓ Reported submission %d: %s\n", id, response.Report.Filename)
+			if response.Hidden {
+				fmt.Println("  It has been hidden until a moderator reviews it.")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&reason, "reason", "", "Why: wrong_title, wrong_episode, spam or offensive")
+	cmd.Flags().StringVar(&details, "details", "", "Optional explanation for moderators")
+	cmd.MarkFlagRequired("reason")
+
+	return cmd
+}
@@ -101,10 +101,20 @@ func newUploadCmd() *cobra.Command {
 				return fmt.Errorf("upload failed: %w", err)
 			}
 
+			if submission.DuplicateOf != nil {
+				fmt.Printf("\nThis name was already submitted for this file, so your upload counts as an upvote for it.\n")
+				fmt.Printf("Filename: %s\n", submission.Filename)
+				fmt.Printf("Submission ID: %d\n", submission.ID)
+				return nil
+			}
+
 			fmt.Printf("\nSubmission uploaded successfully!\n")
 			fmt.Printf("Filename: %s\n", submission.Filename)
 			fmt.Printf("Hash: %s\n", result.Hash)
 			fmt.Printf("Submission ID: %d\n", submission.ID)
+			if submission.Pending {
+				fmt.Println("\nYour submission will appear in lookups and searches once a moderator approves it.")
+			}
 
 			return nil
 		},
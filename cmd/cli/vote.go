@@ -17,8 +17,11 @@ func newVoteCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "vote <file>",
 		Short: "Vote on naming submissions for a file",
-		Long:  "Interactively view and vote on naming submissions for a media file.",
-		Args:  cobra.ExactArgs(1),
+		Long: `Interactively view and vote on naming submissions for a media file.
+
+Your current vote on each submission is shown. Casting the same vote again,
+or answering 'remove', takes it back.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			filePath := args[0]
 
@@ -37,7 +40,7 @@ func newVoteCmd() *cobra.Command {
 
 			// Lookup naming options
 			fmt.Println("Looking up naming options...")
-			response, err := client.Lookup(result.Hash)
+			response, err := client.Lookup(result.Hash, "")
 			if err != nil {
 				return fmt.Errorf("lookup failed: %w", err)
 			}
@@ -51,8 +54,11 @@ func newVoteCmd() *cobra.Command {
 			fmt.Printf("\nFound %d naming option(s):\n\n", len(response.Submissions))
 			for i, submission := range response.Submissions {
 				fmt.Printf("[%d] %s\n", i+1, submission.Filename)
-				fmt.Printf("    Submitted by: %s\n", submission.Username)
+				fmt.Printf("    Submitted by: %s (reputation %d, %s)\n", submission.Username, submission.UserReputation, submission.UserTrustLevel)
 				fmt.Printf("    Votes: %d (↑%d ↓%d)\n", submission.VoteScore, submission.Upvotes, submission.Downvotes)
+				if current := myVote(submission); current != 0 {
+					fmt.Printf("    Your vote: %s\n", voteLabel(current))
+				}
 				if submission.Metadata != nil && submission.Metadata.Title != nil {
 					fmt.Printf("    Title: %s", *submission.Metadata.Title)
 					if submission.Metadata.Year != nil {
@@ -80,9 +86,14 @@ func newVoteCmd() *cobra.Command {
 			}
 
 			selectedSubmission := response.Submissions[selection-1]
+			current := myVote(selectedSubmission)
 
 			// Ask for vote type
-			fmt.Print("\nUpvote or downvote? (up/down): ")
+			if current != 0 {
+				fmt.Printf("\nYou %s this. Vote the same again to remove it. (up/down/remove): ", voteLabel(current))
+			} else {
+				fmt.Print("\nUpvote or downvote? (up/down): ")
+			}
 			voteInput, _ := reader.ReadString('\n')
 			voteInput = strings.TrimSpace(strings.ToLower(voteInput))
 
@@ -92,25 +103,31 @@ func newVoteCmd() *cobra.Command {
 				voteType = models.VoteUp
 			case "down", "downvote", "d", "-1":
 				voteType = models.VoteDown
+			case "remove", "r", "0":
+				if current == 0 {
+					return fmt.Errorf("you have not voted on this submission")
+				}
+				voteType = current
 			default:
-				return fmt.Errorf("invalid vote type (use 'up' or 'down')")
+				return fmt.Errorf("invalid vote type (use 'up', 'down' or 'remove')")
 			}
 
-			// Submit vote
-			if err := client.Vote(selectedSubmission.ID, voteType); err != nil {
-				return fmt.Errorf("vote failed: %w", err)
-			}
-
-			voteAction := "upvoted"
-			if voteType == models.VoteDown {
-				voteAction = "downvoted"
+			if voteType == current {
+				// Same vote again toggles it off
+				if err := client.DeleteVote(selectedSubmission.ID); err != nil {
+					return fmt.Errorf("failed to remove vote: %w", err)
+				}
+				fmt.Printf("\n✓ Removed your vote on: %s\n", selectedSubmission.Filename)
+			} else {
+				if err := client.Vote(selectedSubmission.ID, voteType); err != nil {
+					return fmt.Errorf("vote failed: %w", err)
+				}
+				fmt.Printf("\n✓ Successfully %s: %s\n", voteLabel(voteType), selectedSubmission.Filename)
 			}
 
-			fmt.Printf("\n✓ Successfully %s: %s\n", voteAction, selectedSubmission.Filename)
-
 			// Show updated results
 			fmt.Println("\nFetching updated vote counts...")
-			updatedResponse, err := client.Lookup(result.Hash)
+			updatedResponse, err := client.Lookup(result.Hash, "")
 			if err == nil && len(updatedResponse.Submissions) > 0 {
 				fmt.Println("\nUpdated rankings:")
 				for i, submission := range updatedResponse.Submissions {
@@ -118,9 +135,13 @@ func newVoteCmd() *cobra.Command {
 					if submission.ID == selectedSubmission.ID {
 						prefix = "→ "
 					}
-					fmt.Printf("%s[%d] %s - Votes: %d (↑%d ↓%d)\n",
+					fmt.Printf("%s[%d] %s - Votes: %d (↑%d ↓%d)",
 						prefix, i+1, submission.Filename,
 						submission.VoteScore, submission.Upvotes, submission.Downvotes)
+					if current := myVote(submission); current != 0 {
+						fmt.Printf(" - you %s", voteLabel(current))
+					}
+					fmt.Println()
 				}
 			}
 
@@ -130,3 +151,20 @@ func newVoteCmd() *cobra.Command {
 
 	return cmd
 }
+
+// myVote returns the caller's vote on a submission from an authenticated
+// lookup, or 0 if they have not voted
+func myVote(submission models.SubmissionWithVotes) models.VoteType {
+	if submission.MyVote == nil {
+		return 0
+	}
+	return *submission.MyVote
+}
+
+// voteLabel describes a vote in the past tense, e.g. "upvoted"
+func voteLabel(voteType models.VoteType) string {
+	if voteType == models.VoteDown {
+		return "downvoted"
+	}
+	return "upvoted"
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/quentinsteinke/mkvmender/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// defaultConfigFile is used when it exists and no other file was given
+const defaultConfigFile = "mkvmender-server.yaml"
+
+// configFlags holds command-line overrides, which take precedence over the
+// config file and environment
+type configFlags struct {
+	configFile    string
+	listen        string
+	databaseURL   string
+	migrationsDir string
+	frontendPath  string
+	logLevel      string
+	logFormat     string
+}
+
+// register adds the flags as persistent flags on cmd
+func (f *configFlags) register(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&f.configFile, "config", "c", "", "Path to YAML config file (default: $MKVMENDER_CONFIG or ./"+defaultConfigFile+")")
+	flags.StringVar(&f.listen, "listen", "", "Address to listen on, e.g. :8080")
+	flags.StringVar(&f.databaseURL, "database-url", "", "Database URL")
+	flags.StringVar(&f.migrationsDir, "migrations-dir", "", "Directory containing SQL migrations")
+	flags.StringVar(&f.frontendPath, "frontend-path", "", "Directory containing the static frontend")
+	flags.StringVar(&f.logLevel, "log-level", "", "Log level: debug, info, warn or error")
+	flags.StringVar(&f.logFormat, "log-format", "", "Log format: text or json")
+}
+
+// path returns the config file to load, or "" for none
+func (f *configFlags) path() string {
+	if f.configFile != "" {
+		return f.configFile
+	}
+	if env := os.Getenv("MKVMENDER_CONFIG"); env != "" {
+		return env
+	}
+	if _, err := os.Stat(defaultConfigFile); err == nil {
+		return defaultConfigFile
+	}
+	return ""
+}
+
+// load resolves the full configuration for cmd
+func (f *configFlags) load(cmd *cobra.Command) (*config.Config, error) {
+	cfg, err := config.Load(f.path())
+	if err != nil {
+		return nil, err
+	}
+
+	flags := cmd.Flags()
+	overrides := []struct {
+		name string
+		dst  *string
+		val  string
+	}{
+		{"listen", &cfg.Server.Listen, f.listen},
+		{"database-url", &cfg.Database.URL, f.databaseURL},
+		{"migrations-dir", &cfg.Database.MigrationsDir, f.migrationsDir},
+		{"frontend-path", &cfg.Server.FrontendPath, f.frontendPath},
+		{"log-level", &cfg.Logging.Level, f.logLevel},
+		{"log-format", &cfg.Logging.Format, f.logFormat},
+	}
+	for _, o := range overrides {
+		if flags.Changed(o.name) {
+			*o.dst = o.val
+		}
+	}
+
+	return cfg, nil
+}
+
+func newConfigCmd(flags *configFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect server configuration",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Check the configuration for errors",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := flags.load(cmd)
+			if err != nil {
+				return err
+			}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+
+			source := flags.path()
+			if source == "" {
+				source = "defaults and environment"
+			}
+			fmt.Printf("Configuration is valid (%s)\n", source)
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "print",
+		Short: "Print the effective configuration as YAML",
+		Long: `Print the configuration after applying the config file, environment
+variables and flags. Secrets are redacted.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := flags.load(cmd)
+			if err != nil {
+				return err
+			}
+
+			out, err := cfg.Redacted().YAML()
+			if err != nil {
+				return fmt.Errorf("failed to render configuration: %w", err)
+			}
+			fmt.Print(string(out))
+			return nil
+		},
+	})
+
+	return cmd
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/quentinsteinke/mkvmender/internal/database"
+)
+
+// backgroundJob is periodic maintenance work run while the server is up
+type backgroundJob struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context, logger *slog.Logger) error
+}
+
+// startJobs runs each job on its own ticker until ctx is cancelled. Jobs
+// run once at startup so work that piled up while the server was down is
+// handled promptly.
+func startJobs(ctx context.Context, logger *slog.Logger, jobs []backgroundJob) {
+	for _, job := range jobs {
+		go func(job backgroundJob) {
+			jobLogger := logger.With("job", job.name)
+			ticker := time.NewTicker(job.interval)
+			defer ticker.Stop()
+
+			for {
+				if err := job.run(ctx, jobLogger); err != nil {
+					jobLogger.Error("background job failed", "error", err)
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(job)
+	}
+}
+
+// reactivateSuspensionsJob lifts temporary suspensions that have run out, so
+// admin listings and stats reflect them even if the user never returns
+func reactivateSuspensionsJob(db *database.DB) backgroundJob {
+	return backgroundJob{
+		name:     "reactivate_suspensions",
+		interval: time.Minute,
+		run: func(ctx context.Context, logger *slog.Logger) error {
+			count, err := db.ReactivateExpiredSuspensions()
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				logger.Info("reactivated users after suspension expired", "count", count)
+			}
+			return nil
+		},
+	}
+}
+
+// reputationJob recalculates reputation, trust levels and vote weights from
+// the current votes
+func reputationJob(db *database.DB) backgroundJob {
+	return backgroundJob{
+		name:     "reputation",
+		interval: 15 * time.Minute,
+		run: func(ctx context.Context, logger *slog.Logger) error {
+			changed, err := db.RecalculateReputation()
+			if err != nil {
+				return err
+			}
+			if changed > 0 {
+				logger.Info("updated trust levels", "count", changed)
+			}
+			return nil
+		},
+	}
+}
+
+// voteManipulationJob looks for suspicious voting and queues it for
+// moderator review, neutralizing the votes straight away if configured
+func voteManipulationJob(db *database.DB, neutralize bool) backgroundJob {
+	return backgroundJob{
+		name:     "vote_manipulation",
+		interval: time.Hour,
+		run: func(ctx context.Context, logger *slog.Logger) error {
+			flagged, err := db.DetectVoteManipulation(neutralize)
+			if err != nil {
+				return err
+			}
+			if flagged > 0 {
+				logger.Info("flagged suspicious votes for review", "count", flagged, "neutralized", neutralize)
+			}
+			return nil
+		},
+	}
+}
+
+// purgeDeletedJob permanently removes submissions that have been deleted for
+// longer than retention
+func purgeDeletedJob(db *database.DB, retention time.Duration) backgroundJob {
+	return backgroundJob{
+		name:     "purge_deleted",
+		interval: time.Hour,
+		run: func(ctx context.Context, logger *slog.Logger) error {
+			purged, err := db.PurgeDeletedSubmissions(retention)
+			if err != nil {
+				return err
+			}
+			if purged > 0 {
+				logger.Info("purged deleted submissions", "count", purged)
+			}
+			return nil
+		},
+	}
+}
@@ -1,105 +1,246 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/quentinsteinke/mkvmender/internal/config"
 	"github.com/quentinsteinke/mkvmender/internal/database"
 	"github.com/quentinsteinke/mkvmender/internal/handlers"
+	"github.com/quentinsteinke/mkvmender/internal/logging"
+	"github.com/quentinsteinke/mkvmender/internal/metrics"
+	"github.com/quentinsteinke/mkvmender/internal/models"
+	"github.com/quentinsteinke/mkvmender/internal/pow"
+	"github.com/quentinsteinke/mkvmender/internal/ranking"
+	"github.com/quentinsteinke/mkvmender/internal/ratelimit"
+	"github.com/spf13/cobra"
 )
 
+// registrationChallengeTTL is how long a proof-of-work challenge stays valid
+const registrationChallengeTTL = 10 * time.Minute
+
 func main() {
-	// Get port from environment or use default
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	var flags configFlags
+
+	rootCmd := &cobra.Command{
+		Use:   "mkvmender-server",
+		Short: "MKV Mender API server",
+		Long: `Runs the MKV Mender API server.
+
+Configuration is resolved in order of increasing precedence: built-in
+defaults, the YAML config file (--config or MKVMENDER_CONFIG), environment
+variables, then command-line flags.
+
+Use 'mkvmender-server config print' to see the effective configuration.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := flags.load(cmd)
+			if err != nil {
+				return err
+			}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+			return run(cfg)
+		},
+	}
+
+	flags.register(rootCmd)
+	rootCmd.AddCommand(newConfigCmd(&flags))
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run wires up the server and blocks until it has shut down
+func run(cfg *config.Config) error {
+	// Initialize structured logging
+	logger, err := logging.New(cfg.Logging, os.Stderr)
+	if err != nil {
+		return err
 	}
+	slog.SetDefault(logger)
+
+	// Stop on SIGINT/SIGTERM, draining in-flight requests
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	// Initialize database
-	db, err := database.NewFromEnv()
+	db, err := database.New(database.Config{
+		URL:          cfg.Database.URL,
+		AuthToken:    cfg.Database.AuthToken,
+		BusyTimeout:  time.Duration(cfg.Database.BusyTimeout),
+		MaxOpenConns: cfg.Database.MaxOpenConns,
+	})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 	defer db.Close()
 
 	// Run migrations
-	migrationPath := os.Getenv("MIGRATION_PATH")
-	if migrationPath == "" {
-		migrationPath = "migrations/001_initial_schema.sql"
+	applied, err := db.MigrateDir(cfg.Database.MigrationsDir)
+	for _, name := range applied {
+		logger.Info("applied migration", "name", name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	canonicalized, collisions, err := db.BackfillUsernameCanonical()
+	if canonicalized > 0 {
+		logger.Info("normalized existing usernames", "count", canonicalized)
+	}
+	for _, name := range collisions {
+		logger.Warn("username looks like an existing account; left without a canonical form", "username", name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to normalize usernames: %w", err)
+	}
+	migratedKeys, err := db.MigrateLegacyAPIKeys()
+	if migratedKeys > 0 {
+		logger.Info("hashed legacy API keys", "count", migratedKeys)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to migrate API keys: %w", err)
 	}
 
-	if err := db.Migrate(migrationPath); err != nil {
-		log.Printf("Warning: Migration failed: %v", err)
+	// Initialize metrics
+	var m *metrics.Metrics
+	if cfg.Features.Metrics {
+		m = metrics.New(db)
 	}
 
-	// Run admin features migration
-	adminMigrationPath := "migrations/002_add_admin_features.sql"
-	if err := db.Migrate(adminMigrationPath); err != nil {
-		log.Printf("Warning: Admin migration failed: %v", err)
+	// Start periodic maintenance
+	jobs := []backgroundJob{
+		reactivateSuspensionsJob(db),
+		reputationJob(db),
+		voteManipulationJob(db, cfg.Moderation.NeutralizeFlaggedVotes),
+	}
+	if cfg.Moderation.DeletedRetention > 0 {
+		jobs = append(jobs, purgeDeletedJob(db, time.Duration(cfg.Moderation.DeletedRetention)))
+	}
+	startJobs(ctx, logger, jobs)
+
+	// Rate limiters, shared with handlers that act on behalf of other routes
+	limits, err := ratelimit.NewSet(cfg.RateLimitSettings())
+	if err != nil {
+		return fmt.Errorf("invalid rate limit configuration: %w", err)
 	}
 
 	// Initialize handlers
-	h := handlers.New(db)
+	rank, err := ranking.Parse(cfg.Submissions.Ranking)
+	if err != nil {
+		return err
+	}
+	var preModerationBelow models.TrustLevel
+	if err := preModerationBelow.UnmarshalText([]byte(cfg.Moderation.PreModerationBelow)); err != nil {
+		return err
+	}
+	var minDownvoteTrust models.TrustLevel
+	if err := minDownvoteTrust.UnmarshalText([]byte(cfg.Moderation.MinDownvoteTrust)); err != nil {
+		return err
+	}
+	opts := handlers.Options{
+		RegistrationEnabled: cfg.Features.Registration,
+		RegistrationGate:    models.RegistrationGate(cfg.Registration.Gate),
+		ResetVotesOnRename:  cfg.Submissions.ResetVotesOnRename,
+		Ranking:             rank,
+		AutoHideReports:     cfg.Moderation.AutoHideReports,
+		PreModerationBelow:  preModerationBelow,
+		MinDownvoteTrust:    minDownvoteTrust,
+		AccountDeletion:     models.AccountDeletionPolicy(cfg.Submissions.OnAccountDeletion),
+		VoteLimiter:         limits.For("/api/vote"),
+	}
+	if opts.RegistrationGate == models.GateProofOfWork {
+		opts.ProofOfWork, err = pow.NewIssuer(cfg.Registration.PowDifficulty, registrationChallengeTTL)
+		if err != nil {
+			return err
+		}
+	}
+	h := handlers.New(db, m, opts)
 	adminH := handlers.NewAdminHandler(db)
 
 	// Create router
 	mux := http.NewServeMux()
 
+	// Prometheus metrics
+	if m != nil {
+		mux.Handle("/metrics", m.Handler())
+	}
+
+	// Rate limiting: public routes are keyed by client IP, authenticated
+	// routes by API key
+	limitByIP := func(route string, handler http.HandlerFunc) http.Handler {
+		return handlers.RateLimitMiddleware(limits.For(route), handlers.RateLimitByIP(limits))(handler)
+	}
+	limitByKey := func(route string, handler http.HandlerFunc) http.Handler {
+		return handlers.RateLimitMiddleware(limits.For(route), handlers.RateLimitByAPIKey)(handler)
+	}
+
 	// API routes
 	mux.HandleFunc("/api/health", h.HealthHandler)
-	mux.HandleFunc("/api/register", h.RegisterHandler)
-	mux.HandleFunc("/api/lookup", h.LookupHandler)
-	mux.HandleFunc("/api/search", h.SearchHandler)
-
-	// Protected API routes (require authentication)
+	mux.HandleFunc("/api/ready", h.ReadyHandler)
+	mux.Handle("/api/register", limitByIP("/api/register", h.RegisterHandler))
+	mux.Handle("/api/register/challenge", limitByIP("/api/register/challenge", h.RegistrationChallengeHandler))
+	mux.Handle("/api/lookup", handlers.OptionalAuthMiddleware(db, models.ScopeRead)(limitByIP("/api/lookup", h.LookupHandler)))
+	mux.Handle("/api/search", limitByIP("/api/search", h.SearchHandler))
+	mux.Handle("/api/submissions/{id}/history", handlers.OptionalAuthMiddleware(db, models.ScopeRead)(limitByIP("/api/submissions/history", h.SubmissionHistoryHandler)))
+
+	// Protected API routes (require authentication and, where noted, a
+	// key scope)
 	authMiddleware := handlers.AuthMiddleware(db)
 	mux.Handle("/api/verify", authMiddleware(http.HandlerFunc(h.VerifyHandler)))
-	mux.Handle("/api/upload", authMiddleware(http.HandlerFunc(h.UploadHandler)))
-	mux.Handle("/api/vote", authMiddleware(http.HandlerFunc(h.VoteHandler)))
-	mux.Handle("/api/vote/delete", authMiddleware(http.HandlerFunc(h.DeleteVoteHandler)))
-
-	// Admin API routes (require authentication + admin role)
-	adminMiddleware := func(handler http.HandlerFunc) http.Handler {
-		return authMiddleware(handlers.AdminMiddleware(http.HandlerFunc(handler)))
-	}
-	mux.Handle("/api/admin/submissions", adminMiddleware(adminH.ListSubmissionsHandler))
-	mux.Handle("/api/admin/submissions/get", adminMiddleware(adminH.GetSubmissionHandler))
-	mux.Handle("/api/admin/submissions/delete", adminMiddleware(adminH.DeleteSubmissionHandler))
-	mux.Handle("/api/admin/users", adminMiddleware(adminH.ListUsersHandler))
-	mux.Handle("/api/admin/users/get", adminMiddleware(adminH.GetUserHandler))
-	mux.Handle("/api/admin/users/role", adminMiddleware(adminH.ChangeUserRoleHandler))
-	mux.Handle("/api/admin/users/status", adminMiddleware(adminH.ChangeUserStatusHandler))
-	mux.Handle("/api/admin/stats", adminMiddleware(adminH.GetStatsHandler))
-
-	// Serve static frontend files
-	frontendPath := os.Getenv("FRONTEND_PATH")
-	if frontendPath == "" {
-		frontendPath = "frontend/public"
+	mux.Handle("/api/upload", handlers.AuthMiddleware(db, models.ScopeUpload)(limitByKey("/api/upload", h.UploadHandler)))
+	mux.Handle("/api/vote", handlers.AuthMiddleware(db, models.ScopeVote)(limitByKey("/api/vote", h.VoteHandler)))
+	mux.Handle("/api/vote/delete", handlers.AuthMiddleware(db, models.ScopeVote)(limitByKey("/api/vote/delete", h.DeleteVoteHandler)))
+	mux.Handle("/api/submissions/{id}/reports", handlers.AuthMiddleware(db, models.ScopeVote)(limitByKey("/api/submissions/reports", h.ReportSubmissionHandler)))
+	mux.Handle("/api/submissions/{id}", handlers.AuthMiddleware(db, models.ScopeUpload)(limitByKey("/api/submissions", h.SubmissionHandler)))
+	mux.Handle("/api/me/submissions", handlers.AuthMiddleware(db, models.ScopeRead)(http.HandlerFunc(h.MySubmissionsHandler)))
+	mux.Handle("/api/me/export", handlers.AuthMiddleware(db, models.ScopeRead)(limitByKey("/api/me", h.ExportHandler)))
+	mux.Handle("/api/me", handlers.AuthMiddleware(db, models.ScopeRead, models.ScopeUpload, models.ScopeVote)(limitByKey("/api/me", h.DeleteAccountHandler)))
+	mux.Handle("/api/keys", authMiddleware(limitByKey("/api/keys", h.KeysHandler)))
+	mux.Handle("/api/keys/{id}", authMiddleware(limitByKey("/api/keys", h.DeleteKeyHandler)))
+	mux.Handle("/api/keys/{id}/rotate", authMiddleware(limitByKey("/api/keys", h.RotateKeyHandler)))
+
+	// Admin API routes (require authentication, a staff role and the
+	// route's permission in models.RolePermissions)
+	adminMiddleware := func(perm models.Permission, handler http.HandlerFunc) http.Handler {
+		return authMiddleware(handlers.AdminMiddleware(handlers.RequirePermission(perm)(handler)))
+	}
+	for _, route := range adminH.Routes() {
+		mux.Handle(route.Pattern, adminMiddleware(route.Permission, route.Handler))
 	}
 
-	// Check if frontend directory exists
-	if _, err := os.Stat(frontendPath); err == nil {
-		log.Printf("Serving frontend from: %s", frontendPath)
+	// Serve static frontend files
+	frontendPath := cfg.Server.FrontendPath
+	_, statErr := os.Stat(frontendPath)
+	if cfg.Features.Frontend && statErr == nil {
+		logger.Info("serving frontend", "path", frontendPath)
 		fs := http.FileServer(http.Dir(frontendPath))
 		mux.Handle("/", fs)
 	} else {
-		log.Printf("Frontend not found at %s, serving API only", frontendPath)
+		logger.Info("frontend disabled or not found, serving API only", "path", frontendPath)
 		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"message":"MKV Mender API","endpoints":["/api/health","/api/register","/api/lookup","/api/search","/api/upload","/api/vote"]}`))
+			w.Write([]byte(`{"message":"MKV Mender API","endpoints":["/api/health","/api/ready","/api/register","/api/lookup","/api/search","/api/upload","/api/vote"]}`))
 		})
 	}
 
 	// Apply global middleware
-	handler := handlers.LoggingMiddleware(handlers.CORSMiddleware(mux))
+	handler := handlers.LoggingMiddleware(logger)(
+		handlers.MetricsMiddleware(m)(
+			handlers.CORSMiddleware(cfg.CORS.AllowedOrigins)(
+				handlers.MaxBodyMiddleware(cfg.Server.MaxBodyBytes)(mux))))
 
-	// Start server
-	addr := fmt.Sprintf(":%s", port)
-	log.Printf("Server starting on %s", addr)
-	if err := http.ListenAndServe(addr, handler); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
-	}
+	// Start server and block until shutdown completes
+	return runServer(ctx, cfg.Server, handler, logger, h.StartDraining)
 }
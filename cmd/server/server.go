@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/quentinsteinke/mkvmender/internal/config"
+)
+
+// runServer serves handler until ctx is cancelled, then stops accepting new
+// connections and waits up to cfg.ShutdownTimeout for in-flight requests.
+// onShutdown is called as soon as draining begins.
+func runServer(ctx context.Context, cfg config.ServerConfig, handler http.Handler, logger *slog.Logger, onShutdown func()) error {
+	srv := &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           handler,
+		ReadTimeout:       time.Duration(cfg.ReadTimeout),
+		ReadHeaderTimeout: time.Duration(cfg.ReadHeaderTimeout),
+		WriteTimeout:      time.Duration(cfg.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.IdleTimeout),
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+
+	if cfg.TLS.Enabled() {
+		reloader, err := newCertReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile, logger)
+		if err != nil {
+			return err
+		}
+		go reloader.watch(ctx)
+
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("server starting", "addr", cfg.Listen, "tls", cfg.TLS.Enabled())
+		var err error
+		if cfg.TLS.Enabled() {
+			// Certificates come from TLSConfig.GetCertificate
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		serveErr <- err
+	}()
+
+	select {
+	case err := <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down, draining connections", "timeout", time.Duration(cfg.ShutdownTimeout))
+	if onShutdown != nil {
+		onShutdown()
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout))
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("graceful shutdown failed: %w", err)
+	}
+
+	logger.Info("server stopped")
+	return nil
+}
@@ -3,13 +3,16 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/quentinsteinke/mkvmender/internal/models"
+	"github.com/quentinsteinke/mkvmender/internal/pow"
 )
 
 // Client represents an API client
@@ -28,23 +31,96 @@ func New(baseURL, apiKey string) *Client {
 	}
 }
 
-// doRequest performs an HTTP request with authentication
+// maxRateLimitRetries is how many times a rate-limited request is retried
+const maxRateLimitRetries = 3
+
+// maxRetryWait caps how long the client will sleep before retrying a
+// rate-limited request; longer waits are returned as errors instead
+const maxRetryWait = time.Minute
+
+// RateLimitError is returned when the server keeps rejecting requests for
+// exceeding its rate limit
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Message    string
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("rate limited: %s", e.Message)
+	}
+	return fmt.Sprintf("rate limited: retry after %s", e.RetryAfter)
+}
+
+// APIError is returned when the server responds with an error. Code holds
+// the machine-readable error code, if the server sent one.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Err        string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Err, e.Message)
+}
+
+// IsErrorCode reports whether err is an APIError carrying code
+func IsErrorCode(err error, code string) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// doRequest performs an HTTP request with authentication, waiting and
+// retrying when the server responds with 429 Too Many Requests
 func (c *Client) doRequest(method, path string, body interface{}, result interface{}) error {
-	var bodyReader io.Reader
+	var bodyBytes []byte
 	if body != nil {
-		bodyBytes, err := json.Marshal(body)
+		var err error
+		bodyBytes, err = json.Marshal(body)
 		if err != nil {
 			return fmt.Errorf("failed to marshal request body: %w", err)
 		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(method, path, bodyBytes)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests {
+			return c.handleResponse(resp, result)
+		}
+
+		wait := retryAfter(resp.Header)
+		rateErr := &RateLimitError{RetryAfter: wait}
+		var errResp models.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err == nil {
+			rateErr.Message = errResp.Message
+		}
+		resp.Body.Close()
+
+		if attempt >= maxRateLimitRetries || wait > maxRetryWait {
+			return rateErr
+		}
+		time.Sleep(wait)
+	}
+}
+
+// send builds and sends a single HTTP request
+func (c *Client) send(method, path string, bodyBytes []byte) (*http.Response, error) {
+	var bodyReader io.Reader
+	if bodyBytes != nil {
 		bodyReader = bytes.NewReader(bodyBytes)
 	}
 
 	req, err := http.NewRequest(method, c.baseURL+path, bodyReader)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	if body != nil {
+	if bodyBytes != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
 
@@ -54,8 +130,14 @@ func (c *Client) doRequest(method, path string, body interface{}, result interfa
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
+
+	return resp, nil
+}
+
+// handleResponse decodes a response into result or converts it to an error
+func (c *Client) handleResponse(resp *http.Response, result interface{}) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
@@ -63,7 +145,12 @@ func (c *Client) doRequest(method, path string, body interface{}, result interfa
 		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
 			return fmt.Errorf("request failed with status %d", resp.StatusCode)
 		}
-		return fmt.Errorf("%s: %s", errResp.Error, errResp.Message)
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Code:       errResp.Code,
+			Err:        errResp.Error,
+			Message:    errResp.Message,
+		}
 	}
 
 	if result != nil {
@@ -75,19 +162,66 @@ func (c *Client) doRequest(method, path string, body interface{}, result interfa
 	return nil
 }
 
-// Register registers a new user
-func (c *Client) Register(username string) (*models.User, error) {
-	req := map[string]string{"username": username}
+// retryAfter reads how long to wait from the Retry-After header, falling
+// back to RateLimit-Reset and then to one second
+func retryAfter(header http.Header) time.Duration {
+	for _, name := range []string{"Retry-After", "RateLimit-Reset"} {
+		value := header.Get(name)
+		if value == "" {
+			continue
+		}
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if when, err := http.ParseTime(value); err == nil {
+			if wait := time.Until(when); wait > 0 {
+				return wait
+			}
+			return 0
+		}
+	}
+	return time.Second
+}
+
+// Register registers a new user. When the server requires proof of work,
+// a challenge is fetched and solved automatically.
+func (c *Client) Register(req *models.RegisterRequest) (*models.User, error) {
 	var user models.User
-	if err := c.doRequest("POST", "/api/register", req, &user); err != nil {
+	err := c.doRequest("POST", "/api/register", req, &user)
+	if IsErrorCode(err, models.ErrorCodeProofOfWorkRequired) && req.Challenge == "" {
+		challenge, err := c.RegistrationChallenge()
+		if err != nil {
+			return nil, err
+		}
+		solved := *req
+		solved.Challenge = challenge.Challenge
+		solved.Nonce = pow.Solve(challenge.Challenge, challenge.Difficulty)
+		return c.Register(&solved)
+	}
+	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
+// RegistrationChallenge fetches a proof-of-work challenge for registration
+func (c *Client) RegistrationChallenge() (*models.RegistrationChallenge, error) {
+	var challenge models.RegistrationChallenge
+	if err := c.doRequest("GET", "/api/register/challenge", nil, &challenge); err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
 // Lookup looks up naming submissions by hash
-func (c *Client) Lookup(hash string) (*models.HashLookupResponse, error) {
-	path := fmt.Sprintf("/api/lookup?hash=%s", hash)
+func (c *Client) Lookup(hash, rank string) (*models.HashLookupResponse, error) {
+	params := url.Values{}
+	params.Add("hash", hash)
+	if rank != "" {
+		params.Add("rank", rank)
+	}
+
+	path := fmt.Sprintf("/api/lookup?%s", params.Encode())
 	var response models.HashLookupResponse
 	if err := c.doRequest("GET", path, nil, &response); err != nil {
 		return nil, err
@@ -96,12 +230,12 @@ func (c *Client) Lookup(hash string) (*models.HashLookupResponse, error) {
 }
 
 // Upload uploads a new naming submission
-func (c *Client) Upload(req *models.UploadRequest) (*models.NamingSubmission, error) {
-	var submission models.NamingSubmission
-	if err := c.doRequest("POST", "/api/upload", req, &submission); err != nil {
+func (c *Client) Upload(req *models.UploadRequest) (*models.UploadResponse, error) {
+	var resp models.UploadResponse
+	if err := c.doRequest("POST", "/api/upload", req, &resp); err != nil {
 		return nil, err
 	}
-	return &submission, nil
+	return &resp, nil
 }
 
 // Vote votes on a submission
@@ -119,13 +253,106 @@ func (c *Client) DeleteVote(submissionID int64) error {
 	return c.doRequest("DELETE", path, nil, nil)
 }
 
+// MySubmissions lists the caller's own submissions
+func (c *Client) MySubmissions() ([]models.SubmissionWithVotes, error) {
+	var submissions []models.SubmissionWithVotes
+	if err := c.doRequest("GET", "/api/me/submissions", nil, &submissions); err != nil {
+		return nil, err
+	}
+	return submissions, nil
+}
+
+// UpdateSubmission edits one of the caller's submissions
+func (c *Client) UpdateSubmission(id int64, req *models.UpdateSubmissionRequest) (*models.UpdateSubmissionResponse, error) {
+	path := fmt.Sprintf("/api/submissions/%d", id)
+	var response models.UpdateSubmissionResponse
+	if err := c.doRequest("PUT", path, req, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// RetractSubmission deletes one of the caller's submissions
+func (c *Client) RetractSubmission(id int64) error {
+	path := fmt.Sprintf("/api/submissions/%d", id)
+	return c.doRequest("DELETE", path, nil, nil)
+}
+
+// ReportSubmission reports a bad submission to moderators
+func (c *Client) ReportSubmission(id int64, req *models.CreateReportRequest) (*models.CreateReportResponse, error) {
+	path := fmt.Sprintf("/api/submissions/%d/reports", id)
+	var response models.CreateReportResponse
+	if err := c.doRequest("POST", path, req, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// ExportAccount fetches everything the server holds about the caller
+func (c *Client) ExportAccount() (*models.AccountExport, error) {
+	var export models.AccountExport
+	if err := c.doRequest("GET", "/api/me/export", nil, &export); err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// DeleteAccount deletes the caller's account; confirm must be its username
+func (c *Client) DeleteAccount(confirm string) (*models.DeleteAccountResponse, error) {
+	req := models.DeleteAccountRequest{Confirm: confirm}
+	var response models.DeleteAccountResponse
+	if err := c.doRequest("DELETE", "/api/me", req, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// ListKeys lists the caller's API keys
+func (c *Client) ListKeys() ([]models.APIKey, error) {
+	var response models.APIKeyListResponse
+	if err := c.doRequest("GET", "/api/keys", nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Keys, nil
+}
+
+// CreateKey creates a new named API key with the given scopes (the server
+// default when empty); the secret is only returned here
+func (c *Client) CreateKey(name string, scopes []models.Scope) (*models.APIKey, error) {
+	req := models.CreateAPIKeyRequest{Name: name, Scopes: scopes}
+	var key models.APIKey
+	if err := c.doRequest("POST", "/api/keys", req, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// RevokeKey revokes one of the caller's API keys
+func (c *Client) RevokeKey(id int64) error {
+	path := fmt.Sprintf("/api/keys/%d", id)
+	return c.doRequest("DELETE", path, nil, nil)
+}
+
+// RotateKey replaces the secret of one of the caller's API keys
+func (c *Client) RotateKey(id int64) (*models.APIKey, error) {
+	path := fmt.Sprintf("/api/keys/%d/rotate", id)
+	var key models.APIKey
+	if err := c.doRequest("POST", path, nil, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
 // Search searches for naming submissions by title
-func (c *Client) Search(query, sortBy string, useFuzzy bool) (*models.SearchResponse, error) {
+func (c *Client) Search(query, sortBy, rank string, useFuzzy bool) (*models.SearchResponse, error) {
 	params := url.Values{}
 	params.Add("q", query)
 	if sortBy != "" {
 		params.Add("sort", sortBy)
 	}
+	if rank != "" {
+		params.Add("rank", rank)
+	}
 	if !useFuzzy {
 		params.Add("fuzzy", "false")
 	}
@@ -138,6 +365,127 @@ func (c *Client) Search(query, sortBy string, useFuzzy bool) (*models.SearchResp
 	return &response, nil
 }
 
+// AdminListUsers lists users for staff, optionally filtered by role and
+// status (active or suspended), and returns the total matching
+func (c *Client) AdminListUsers(role, status string, page, limit int) ([]models.AdminUserListItem, int, error) {
+	params := url.Values{}
+	params.Add("page", strconv.Itoa(page))
+	params.Add("limit", strconv.Itoa(limit))
+	if role != "" {
+		params.Add("role", role)
+	}
+	if status != "" {
+		params.Add("status", status)
+	}
+
+	var response struct {
+		Users []models.AdminUserListItem `json:"users"`
+		Total int                        `json:"total"`
+	}
+	if err := c.doRequest("GET", "/api/admin/users?"+params.Encode(), nil, &response); err != nil {
+		return nil, 0, err
+	}
+	return response.Users, response.Total, nil
+}
+
+// AdminListSubmissions lists submissions for staff, optionally only one
+// user's or only deleted ones, and returns the total matching
+func (c *Client) AdminListSubmissions(userID int64, sortBy string, deleted bool, page, limit int) ([]models.AdminSubmissionListItem, int, error) {
+	params := url.Values{}
+	params.Add("page", strconv.Itoa(page))
+	params.Add("limit", strconv.Itoa(limit))
+	if userID != 0 {
+		params.Add("user_id", strconv.FormatInt(userID, 10))
+	}
+	if sortBy != "" {
+		params.Add("sort", sortBy)
+	}
+	if deleted {
+		params.Add("deleted", "true")
+	}
+
+	var response struct {
+		Submissions []models.AdminSubmissionListItem `json:"submissions"`
+		Total       int                              `json:"total"`
+	}
+	if err := c.doRequest("GET", "/api/admin/submissions?"+params.Encode(), nil, &response); err != nil {
+		return nil, 0, err
+	}
+	return response.Submissions, response.Total, nil
+}
+
+// AdminSetUserStatus suspends or reactivates a user and returns the
+// server's confirmation
+func (c *Client) AdminSetUserStatus(userID int64, req *models.ChangeStatusRequest) (string, error) {
+	path := fmt.Sprintf("/api/admin/users/status?id=%d", userID)
+	var response models.SuccessResponse
+	if err := c.doRequest("PUT", path, req, &response); err != nil {
+		return "", err
+	}
+	return response.Message, nil
+}
+
+// AdminChangeRole changes a user's role
+func (c *Client) AdminChangeRole(userID int64, role models.UserRole) error {
+	path := fmt.Sprintf("/api/admin/users/role?id=%d", userID)
+	return c.doRequest("PUT", path, models.ChangeRoleRequest{Role: role}, nil)
+}
+
+// AdminDeleteSubmission deletes a submission as a moderator
+func (c *Client) AdminDeleteSubmission(id int64, reason string) error {
+	path := fmt.Sprintf("/api/admin/submissions/delete?id=%d", id)
+	var req models.DeleteSubmissionRequest
+	if reason != "" {
+		req.Reason = &reason
+	}
+	return c.doRequest("DELETE", path, req, nil)
+}
+
+// AdminListKeys lists a user's API keys (admins only)
+func (c *Client) AdminListKeys(userID int64) ([]models.APIKey, error) {
+	path := fmt.Sprintf("/api/admin/keys?id=%d", userID)
+	var response models.APIKeyListResponse
+	if err := c.doRequest("GET", path, nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Keys, nil
+}
+
+// AdminRevokeKey revokes any user's API key (admins only)
+func (c *Client) AdminRevokeKey(keyID int64) error {
+	path := fmt.Sprintf("/api/admin/keys/revoke?id=%d", keyID)
+	return c.doRequest("DELETE", path, nil, nil)
+}
+
+// AdminListActions lists the moderation log, newest first. params holds
+// the filters accepted by GET /api/admin/actions.
+func (c *Client) AdminListActions(params url.Values) ([]models.ModerationLogEntry, int, error) {
+	var response struct {
+		Actions []models.ModerationLogEntry `json:"actions"`
+		Total   int                         `json:"total"`
+	}
+	if err := c.doRequest("GET", "/api/admin/actions?"+params.Encode(), nil, &response); err != nil {
+		return nil, 0, err
+	}
+	return response.Actions, response.Total, nil
+}
+
+// AdminTargetTimeline lists every moderation action on one target, oldest
+// first
+func (c *Client) AdminTargetTimeline(targetType string, targetID int64) ([]models.ModerationLogEntry, error) {
+	params := url.Values{}
+	params.Add("target_type", targetType)
+	params.Add("target_id", strconv.FormatInt(targetID, 10))
+
+	var response struct {
+		Actions []models.ModerationLogEntry `json:"actions"`
+	}
+	if err := c.doRequest("GET", "/api/admin/actions/timeline?"+params.Encode(), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Actions, nil
+}
+
 // Health checks the API health
 func (c *Client) Health() error {
 	var result map[string]string
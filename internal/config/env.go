@@ -0,0 +1,175 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envOverride maps environment variables onto a config field. When several
+// names are listed, the first one that is set wins.
+type envOverride struct {
+	names []string
+	apply func(cfg *Config, value string) error
+}
+
+// envOverrides lists every supported environment variable. The unprefixed
+// names predate the config file and are kept for existing deployments.
+var envOverrides = []envOverride{
+	{[]string{"MKVMENDER_LISTEN"}, func(cfg *Config, v string) error {
+		cfg.Server.Listen = v
+		return nil
+	}},
+	{[]string{"PORT"}, func(cfg *Config, v string) error {
+		// PORT is set by hosting platforms; MKVMENDER_LISTEN takes precedence
+		if os.Getenv("MKVMENDER_LISTEN") == "" {
+			cfg.Server.Listen = ":" + v
+		}
+		return nil
+	}},
+	{[]string{"MKVMENDER_DATABASE_URL", "TURSO_DATABASE_URL"}, func(cfg *Config, v string) error {
+		cfg.Database.URL = v
+		return nil
+	}},
+	{[]string{"MKVMENDER_DATABASE_AUTH_TOKEN", "TURSO_AUTH_TOKEN"}, func(cfg *Config, v string) error {
+		cfg.Database.AuthToken = v
+		return nil
+	}},
+	{[]string{"MKVMENDER_MIGRATIONS_DIR"}, func(cfg *Config, v string) error {
+		cfg.Database.MigrationsDir = v
+		return nil
+	}},
+	{[]string{"MKVMENDER_FRONTEND_PATH", "FRONTEND_PATH"}, func(cfg *Config, v string) error {
+		cfg.Server.FrontendPath = v
+		return nil
+	}},
+	{[]string{"MKVMENDER_TLS_CERT_FILE", "TLS_CERT_FILE"}, func(cfg *Config, v string) error {
+		cfg.Server.TLS.CertFile = v
+		return nil
+	}},
+	{[]string{"MKVMENDER_TLS_KEY_FILE", "TLS_KEY_FILE"}, func(cfg *Config, v string) error {
+		cfg.Server.TLS.KeyFile = v
+		return nil
+	}},
+	{[]string{"MKVMENDER_CORS_ORIGINS"}, func(cfg *Config, v string) error {
+		cfg.CORS.AllowedOrigins = splitList(v)
+		return nil
+	}},
+	{[]string{"MKVMENDER_TRUSTED_PROXIES", "TRUSTED_PROXIES"}, func(cfg *Config, v string) error {
+		cfg.RateLimits.TrustedProxies = splitList(v)
+		return nil
+	}},
+	{[]string{"MKVMENDER_LOG_FORMAT", "LOG_FORMAT"}, func(cfg *Config, v string) error {
+		cfg.Logging.Format = v
+		return nil
+	}},
+	{[]string{"MKVMENDER_LOG_LEVEL", "LOG_LEVEL"}, func(cfg *Config, v string) error {
+		cfg.Logging.Level = v
+		return nil
+	}},
+	{[]string{"MKVMENDER_FEATURE_FRONTEND"}, func(cfg *Config, v string) error {
+		return parseBool(v, &cfg.Features.Frontend)
+	}},
+	{[]string{"MKVMENDER_FEATURE_METRICS"}, func(cfg *Config, v string) error {
+		return parseBool(v, &cfg.Features.Metrics)
+	}},
+	{[]string{"MKVMENDER_FEATURE_REGISTRATION"}, func(cfg *Config, v string) error {
+		return parseBool(v, &cfg.Features.Registration)
+	}},
+	{[]string{"MKVMENDER_REGISTRATION_GATE"}, func(cfg *Config, v string) error {
+		cfg.Registration.Gate = v
+		return nil
+	}},
+	{[]string{"MKVMENDER_REGISTRATION_POW_DIFFICULTY"}, func(cfg *Config, v string) error {
+		difficulty, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		cfg.Registration.PowDifficulty = difficulty
+		return nil
+	}},
+	{[]string{"MKVMENDER_RANKING"}, func(cfg *Config, v string) error {
+		cfg.Submissions.Ranking = v
+		return nil
+	}},
+	{[]string{"MKVMENDER_ON_ACCOUNT_DELETION"}, func(cfg *Config, v string) error {
+		cfg.Submissions.OnAccountDeletion = v
+		return nil
+	}},
+	{[]string{"MKVMENDER_NEUTRALIZE_FLAGGED_VOTES"}, func(cfg *Config, v string) error {
+		return parseBool(v, &cfg.Moderation.NeutralizeFlaggedVotes)
+	}},
+	{[]string{"MKVMENDER_AUTO_HIDE_REPORTS"}, func(cfg *Config, v string) error {
+		reports, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		cfg.Moderation.AutoHideReports = reports
+		return nil
+	}},
+	{[]string{"MKVMENDER_PRE_MODERATION_BELOW"}, func(cfg *Config, v string) error {
+		cfg.Moderation.PreModerationBelow = v
+		return nil
+	}},
+	{[]string{"MKVMENDER_MIN_DOWNVOTE_TRUST"}, func(cfg *Config, v string) error {
+		cfg.Moderation.MinDownvoteTrust = v
+		return nil
+	}},
+	{[]string{"MKVMENDER_DELETED_RETENTION"}, func(cfg *Config, v string) error {
+		retention, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		cfg.Moderation.DeletedRetention = Duration(retention)
+		return nil
+	}},
+}
+
+// applyEnv applies environment variable overrides to cfg
+func applyEnv(cfg *Config) error {
+	for _, override := range envOverrides {
+		for _, name := range override.names {
+			value, ok := os.LookupEnv(name)
+			if !ok || value == "" {
+				continue
+			}
+			if err := override.apply(cfg, value); err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// EnvVars returns the names of all supported environment variables
+func EnvVars() []string {
+	var names []string
+	for _, override := range envOverrides {
+		names = append(names, override.names...)
+	}
+	return names
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseBool parses a boolean environment value into dst
+func parseBool(value string, dst *bool) error {
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*dst = parsed
+	return nil
+}
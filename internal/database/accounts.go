@@ -0,0 +1,287 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/quentinsteinke/mkvmender/internal/models"
+	"github.com/quentinsteinke/mkvmender/internal/username"
+)
+
+// ghostUsernames are the names tried, in order, for the account that keeps
+// what deleted users leave behind. "deleted" is reserved, but may belong to
+// an account registered before it was; "[deleted]" can never be registered.
+var ghostUsernames = []string{"deleted", "[deleted]"}
+
+// ExportUserData gathers everything stored about a user: their profile, API
+// keys (without secrets), submissions with metadata, votes and reports
+func (db *DB) ExportUserData(userID int64) (*models.AccountExport, error) {
+	user, err := db.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &models.AccountExport{
+		ExportedAt: time.Now().UTC(),
+		User:       *user,
+	}
+
+	if export.APIKeys, err = db.ListAPIKeys(userID); err != nil {
+		return nil, err
+	}
+	if export.Submissions, err = db.exportSubmissions(userID); err != nil {
+		return nil, err
+	}
+	if export.Votes, err = db.exportVotes(userID); err != nil {
+		return nil, err
+	}
+	if export.Reports, err = db.exportReports(userID); err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// exportSubmissions retrieves all of a user's submissions, including hidden,
+// pending and deleted ones, oldest first
+func (db *DB) exportSubmissions(userID int64) ([]models.ExportedSubmission, error) {
+	query := `
+		SELECT ns.id, ns.hash_id, ns.user_id, ns.filename, ns.pending, ns.created_at, ns.updated_at,
+		       fh.hash, ns.hidden, ns.deleted_at
+		FROM naming_submissions ns
+		JOIN file_hashes fh ON fh.id = ns.hash_id
+		WHERE ns.user_id = ?
+		ORDER BY ns.created_at, ns.id
+	`
+
+	rows, err := db.query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query submissions: %w", err)
+	}
+	defer rows.Close()
+
+	submissions := []models.ExportedSubmission{}
+	for rows.Next() {
+		var s models.ExportedSubmission
+		var deletedAt sql.NullTime
+		err := rows.Scan(
+			&s.ID,
+			&s.HashID,
+			&s.UserID,
+			&s.Filename,
+			&s.Pending,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+			&s.Hash,
+			&s.Hidden,
+			&deletedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan submission: %w", err)
+		}
+		if deletedAt.Valid {
+			s.DeletedAt = &deletedAt.Time
+		}
+		submissions = append(submissions, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	rows.Close()
+
+	for i := range submissions {
+		if submissions[i].Metadata, err = db.GetMetadataBySubmissionID(submissions[i].ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return submissions, nil
+}
+
+// exportVotes retrieves every vote a user has cast, oldest first
+func (db *DB) exportVotes(userID int64) ([]models.Vote, error) {
+	query := `
+		SELECT id, submission_id, user_id, vote_type, created_at, updated_at
+		FROM votes
+		WHERE user_id = ?
+		ORDER BY created_at, id
+	`
+
+	rows, err := db.query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query votes: %w", err)
+	}
+	defer rows.Close()
+
+	votes := []models.Vote{}
+	for rows.Next() {
+		var v models.Vote
+		if err := rows.Scan(&v.ID, &v.SubmissionID, &v.UserID, &v.VoteType, &v.CreatedAt, &v.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan vote: %w", err)
+		}
+		votes = append(votes, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return votes, nil
+}
+
+// exportReports retrieves every report a user has filed, oldest first
+func (db *DB) exportReports(userID int64) ([]models.SubmissionReport, error) {
+	query := `
+		SELECT ` + reportColumns + `
+		FROM submission_reports r
+		JOIN naming_submissions ns ON ns.id = r.submission_id
+		JOIN users u ON u.id = r.reporter_id
+		WHERE r.reporter_id = ?
+		ORDER BY r.created_at, r.id
+	`
+
+	rows, err := db.query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []models.SubmissionReport{}
+	for rows.Next() {
+		r, err := scanReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan report: %w", err)
+		}
+		reports = append(reports, *r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return reports, nil
+}
+
+// DeleteAccount deletes a user along with their API keys and votes. Under
+// DeletionReassign their submissions are kept and credited to the ghost
+// account; under DeletionDelete they are deleted for good. Revisions,
+// reports and moderation actions the user made on other content are always
+// kept and credited to the ghost account, so submissions their reports hid
+// stay in the report queue. It returns how many submissions were reassigned
+// or deleted.
+func (db *DB) DeleteAccount(userID int64, policy models.AccountDeletionPolicy) (int, error) {
+	var submissions int64
+	err := db.inTx(func(tx *dbTx) error {
+		ghostID, err := ghostUserID(tx)
+		if err != nil {
+			return err
+		}
+
+		var result sql.Result
+		if policy == models.DeletionDelete {
+			// Foreign keys with CASCADE will handle deletion of votes,
+			// metadata, revisions and reports
+			result, err = tx.exec(`DELETE FROM naming_submissions WHERE user_id = ?`, userID)
+		} else {
+			result, err = tx.exec(`UPDATE naming_submissions SET user_id = ? WHERE user_id = ?`, ghostID, userID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to remove submissions: %w", err)
+		}
+		if submissions, err = result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		for _, query := range []string{
+			`UPDATE submission_revisions SET user_id = ? WHERE user_id = ?`,
+			`UPDATE moderation_actions SET admin_id = ? WHERE admin_id = ?`,
+		} {
+			if _, err := tx.exec(query, ghostID, userID); err != nil {
+				return fmt.Errorf("failed to reassign history: %w", err)
+			}
+		}
+
+		// The ghost holds one report per submission; where it already has
+		// one, the user's report goes with the account
+		_, err = tx.exec(`
+			UPDATE submission_reports SET reporter_id = ?
+			WHERE reporter_id = ? AND NOT EXISTS (
+				SELECT 1 FROM submission_reports r
+				WHERE r.submission_id = submission_reports.submission_id AND r.reporter_id = ?
+			)
+		`, ghostID, userID, ghostID)
+		if err != nil {
+			return fmt.Errorf("failed to reassign reports: %w", err)
+		}
+
+		result, err = tx.exec(`DELETE FROM users WHERE id = ?`, userID)
+		if err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows == 0 {
+			return fmt.Errorf("user not found")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(submissions), nil
+}
+
+// ghostUserID returns the ID of the ghost account, marked by is_ghost,
+// creating it the first time it is needed under the first free name in
+// ghostUsernames. It cannot sign in: it is inactive and has no API keys.
+func ghostUserID(tx *dbTx) (int64, error) {
+	var id int64
+	err := tx.queryRow(`SELECT id FROM users WHERE is_ghost = TRUE`).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to get ghost user: %w", err)
+	}
+
+	// Look before inserting: a failed insert would abort a PostgreSQL
+	// transaction
+	name := ""
+	for _, candidate := range ghostUsernames {
+		var taken bool
+		err := tx.queryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE username_canonical = ?)`,
+			username.Canonical(candidate)).Scan(&taken)
+		if err != nil {
+			return 0, fmt.Errorf("failed to check ghost username: %w", err)
+		}
+		if !taken {
+			name = candidate
+			break
+		}
+	}
+	if name == "" {
+		return 0, fmt.Errorf("failed to create ghost user: every ghost username is taken")
+	}
+
+	placeholder, err := generatePlaceholderAPIKey()
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	err = tx.queryRow(`
+		INSERT INTO users (username, username_canonical, api_key, role, is_active, is_ghost)
+		VALUES (?, ?, ?, 'user', FALSE, TRUE)
+		RETURNING id
+	`, name, username.Canonical(name), placeholder).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create ghost user: %w", err)
+	}
+
+	return id, nil
+}
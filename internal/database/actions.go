@@ -0,0 +1,165 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/quentinsteinke/mkvmender/internal/models"
+)
+
+// ActionFilter narrows a moderation log listing. Zero fields match every
+// action.
+type ActionFilter struct {
+	AdminID    *int64
+	ActionType string
+	TargetType string
+	TargetID   *int64
+	Since      *time.Time // Inclusive
+	Until      *time.Time // Exclusive
+}
+
+// where builds the WHERE clause and arguments for the filter
+func (f ActionFilter) where() (string, []interface{}) {
+	whereClause := "WHERE 1=1"
+	args := []interface{}{}
+
+	if f.AdminID != nil {
+		whereClause += " AND ma.admin_id = ?"
+		args = append(args, *f.AdminID)
+	}
+	if f.ActionType != "" {
+		whereClause += " AND ma.action_type = ?"
+		args = append(args, f.ActionType)
+	}
+	if f.TargetType != "" {
+		whereClause += " AND ma.target_type = ?"
+		args = append(args, f.TargetType)
+	}
+	if f.TargetID != nil {
+		whereClause += " AND ma.target_id = ?"
+		args = append(args, *f.TargetID)
+	}
+	if f.Since != nil {
+		whereClause += " AND ma.created_at >= ?"
+		args = append(args, dbTime(*f.Since))
+	}
+	if f.Until != nil {
+		whereClause += " AND ma.created_at < ?"
+		args = append(args, dbTime(*f.Until))
+	}
+
+	return whereClause, args
+}
+
+// moderationLogColumns is selected from moderation_actions ma joined with
+// the acting admin (u), in the order scanModerationLogEntry expects
+const moderationLogColumns = `ma.id, ma.admin_id, COALESCE(u.username, ''), ma.action_type,
+	ma.target_type, ma.target_id, ma.reason, ma.expires_at, ma.details, ma.created_at`
+
+// scanModerationLogEntry scans a row selected with moderationLogColumns
+func scanModerationLogEntry(row interface{ Scan(...interface{}) error }) (*models.ModerationLogEntry, error) {
+	var e models.ModerationLogEntry
+	var reason sql.NullString
+	var expiresAt sql.NullTime
+	var details sql.NullString
+	err := row.Scan(
+		&e.ID,
+		&e.AdminID,
+		&e.AdminUsername,
+		&e.ActionType,
+		&e.TargetType,
+		&e.TargetID,
+		&reason,
+		&expiresAt,
+		&details,
+		&e.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if reason.Valid {
+		e.Reason = &reason.String
+	}
+	if expiresAt.Valid {
+		e.ExpiresAt = &expiresAt.Time
+	}
+	if details.Valid {
+		e.Details = json.RawMessage(details.String)
+	}
+	return &e, nil
+}
+
+// ListModerationActions retrieves a paginated list of logged moderation
+// actions matching filter, newest first
+func (db *DB) ListModerationActions(filter ActionFilter, page, limit int) ([]models.ModerationLogEntry, int, error) {
+	offset := (page - 1) * limit
+	whereClause, args := filter.where()
+
+	var total int
+	err := db.queryRow(`SELECT COUNT(*) FROM moderation_actions ma `+whereClause, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count moderation actions: %w", err)
+	}
+
+	query := `
+		SELECT ` + moderationLogColumns + `
+		FROM moderation_actions ma
+		LEFT JOIN users u ON u.id = ma.admin_id
+		` + whereClause + `
+		ORDER BY ma.created_at DESC, ma.id DESC
+		LIMIT ? OFFSET ?
+	`
+
+	args = append(args, limit, offset)
+	actions, err := db.queryModerationLog(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return actions, total, nil
+}
+
+// GetTargetTimeline retrieves every logged moderation action on one user,
+// submission, invite or vote flag, oldest first, including bulk actions
+// linked to it
+func (db *DB) GetTargetTimeline(targetType string, targetID int64) ([]models.ModerationLogEntry, error) {
+	query := `
+		SELECT ` + moderationLogColumns + `
+		FROM moderation_actions ma
+		LEFT JOIN users u ON u.id = ma.admin_id
+		WHERE (ma.target_type = ? AND ma.target_id = ?)
+		   OR EXISTS (
+			SELECT 1 FROM moderation_action_targets t
+			WHERE t.action_id = ma.id AND t.target_type = ? AND t.target_id = ?
+		   )
+		ORDER BY ma.created_at, ma.id
+	`
+
+	return db.queryModerationLog(query, targetType, targetID, targetType, targetID)
+}
+
+// queryModerationLog runs a query selecting moderationLogColumns
+func (db *DB) queryModerationLog(query string, args ...interface{}) ([]models.ModerationLogEntry, error) {
+	rows, err := db.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query moderation actions: %w", err)
+	}
+	defer rows.Close()
+
+	actions := []models.ModerationLogEntry{}
+	for rows.Next() {
+		e, err := scanModerationLogEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan moderation action: %w", err)
+		}
+		actions = append(actions, *e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return actions, nil
+}
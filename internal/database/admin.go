@@ -7,13 +7,18 @@ import (
 	"github.com/quentinsteinke/mkvmender/internal/models"
 )
 
-// AdminListSubmissions retrieves a paginated list of submissions with filters
-func (db *DB) AdminListSubmissions(page, limit int, userID *int64, sortBy string) ([]models.AdminSubmissionListItem, int, error) {
+// AdminListSubmissions retrieves a paginated list of submissions with
+// filters. With deleted set it lists only deleted submissions, otherwise
+// only live ones.
+func (db *DB) AdminListSubmissions(page, limit int, userID *int64, sortBy string, deleted bool) ([]models.AdminSubmissionListItem, int, error) {
 	// Calculate offset
 	offset := (page - 1) * limit
 
 	// Build WHERE clause
-	whereClause := "WHERE 1=1"
+	whereClause := "WHERE ns.deleted_at IS NULL"
+	if deleted {
+		whereClause = "WHERE ns.deleted_at IS NOT NULL"
+	}
 	args := []interface{}{}
 
 	if userID != nil {
@@ -38,7 +43,7 @@ func (db *DB) AdminListSubmissions(page, limit int, userID *int64, sortBy string
 	`, whereClause)
 
 	var total int
-	err := db.conn.QueryRow(countQuery, args...).Scan(&total)
+	err := db.queryRow(countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count submissions: %w", err)
 	}
@@ -51,6 +56,10 @@ func (db *DB) AdminListSubmissions(page, limit int, userID *int64, sortBy string
 			ns.user_id,
 			ns.filename,
 			ns.created_at,
+			ns.hidden,
+			ns.pending,
+			ns.deleted_at,
+			ns.deleted_by,
 			fh.hash,
 			fh.file_size,
 			fh.media_type,
@@ -65,14 +74,14 @@ func (db *DB) AdminListSubmissions(page, limit int, userID *int64, sortBy string
 		LEFT JOIN votes v ON ns.id = v.submission_id
 		LEFT JOIN naming_metadata nm ON ns.id = nm.submission_id
 		%s
-		GROUP BY ns.id, ns.hash_id, ns.user_id, ns.filename, ns.created_at,
-		         fh.hash, fh.file_size, fh.media_type, u.username, u.role
+		GROUP BY ns.id, ns.hash_id, ns.user_id, ns.filename, ns.created_at, ns.hidden, ns.pending,
+		         ns.deleted_at, ns.deleted_by, fh.hash, fh.file_size, fh.media_type, u.username, u.role, nm.title
 		%s
 		LIMIT ? OFFSET ?
 	`, whereClause, orderBy)
 
 	args = append(args, limit, offset)
-	rows, err := db.conn.Query(query, args...)
+	rows, err := db.query(query, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to query submissions: %w", err)
 	}
@@ -81,12 +90,18 @@ func (db *DB) AdminListSubmissions(page, limit int, userID *int64, sortBy string
 	var submissions []models.AdminSubmissionListItem
 	for rows.Next() {
 		var item models.AdminSubmissionListItem
+		var deletedAt sql.NullTime
+		var deletedBy sql.NullInt64
 		err := rows.Scan(
 			&item.ID,
 			&item.HashID,
 			&item.UserID,
 			&item.Filename,
 			&item.CreatedAt,
+			&item.Hidden,
+			&item.Pending,
+			&deletedAt,
+			&deletedBy,
 			&item.Hash,
 			&item.FileSize,
 			&item.MediaType,
@@ -99,6 +114,12 @@ func (db *DB) AdminListSubmissions(page, limit int, userID *int64, sortBy string
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan submission: %w", err)
 		}
+		if deletedAt.Valid {
+			item.DeletedAt = &deletedAt.Time
+		}
+		if deletedBy.Valid {
+			item.DeletedBy = &deletedBy.Int64
+		}
 		submissions = append(submissions, item)
 	}
 
@@ -124,9 +145,9 @@ func (db *DB) AdminListUsers(page, limit int, role, status string) ([]models.Adm
 	}
 
 	if status == "active" {
-		whereClause += " AND u.is_active = 1"
+		whereClause += " AND u.is_active = TRUE"
 	} else if status == "suspended" {
-		whereClause += " AND u.is_active = 0"
+		whereClause += " AND u.is_active = FALSE"
 	}
 
 	// Get total count
@@ -137,7 +158,7 @@ func (db *DB) AdminListUsers(page, limit int, role, status string) ([]models.Adm
 	`, whereClause)
 
 	var total int
-	err := db.conn.QueryRow(countQuery, args...).Scan(&total)
+	err := db.queryRow(countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count users: %w", err)
 	}
@@ -149,18 +170,19 @@ func (db *DB) AdminListUsers(page, limit int, role, status string) ([]models.Adm
 			u.username,
 			u.role,
 			u.is_active,
+			u.suspended_until,
 			COUNT(ns.id) as submission_count,
 			u.created_at
 		FROM users u
-		LEFT JOIN naming_submissions ns ON u.id = ns.user_id
+		LEFT JOIN naming_submissions ns ON u.id = ns.user_id AND ns.deleted_at IS NULL
 		%s
-		GROUP BY u.id, u.username, u.role, u.is_active, u.created_at
+		GROUP BY u.id, u.username, u.role, u.is_active, u.suspended_until, u.created_at
 		ORDER BY u.created_at DESC
 		LIMIT ? OFFSET ?
 	`, whereClause)
 
 	args = append(args, limit, offset)
-	rows, err := db.conn.Query(query, args...)
+	rows, err := db.query(query, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to query users: %w", err)
 	}
@@ -169,17 +191,22 @@ func (db *DB) AdminListUsers(page, limit int, role, status string) ([]models.Adm
 	var users []models.AdminUserListItem
 	for rows.Next() {
 		var user models.AdminUserListItem
+		var suspendedUntil sql.NullTime
 		err := rows.Scan(
 			&user.ID,
 			&user.Username,
 			&user.Role,
 			&user.IsActive,
+			&suspendedUntil,
 			&user.SubmissionCount,
 			&user.CreatedAt,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
 		}
+		if suspendedUntil.Valid {
+			user.SuspendedUntil = &suspendedUntil.Time
+		}
 		users = append(users, user)
 	}
 
@@ -190,41 +217,32 @@ func (db *DB) AdminListUsers(page, limit int, role, status string) ([]models.Adm
 	return users, total, nil
 }
 
-// DeleteSubmission deletes a submission and all associated data
-func (db *DB) DeleteSubmission(submissionID int64) error {
-	// Foreign keys with CASCADE will handle deletion of votes and metadata
-	query := `DELETE FROM naming_submissions WHERE id = ?`
-
-	result, err := db.conn.Exec(query, submissionID)
-	if err != nil {
-		return fmt.Errorf("failed to delete submission: %w", err)
-	}
-
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	if rows == 0 {
-		return fmt.Errorf("submission not found")
-	}
-
-	return nil
+// LogModerationAction logs an admin action
+func (db *DB) LogModerationAction(action *models.ModerationAction) error {
+	return logModerationAction(db, action)
 }
 
-// LogModerationAction logs an admin action
-func (db *DB) LogModerationAction(adminID int64, actionType, targetType string, targetID int64, reason string) error {
+// logModerationAction inserts action with q, setting its ID
+func logModerationAction(q querier, action *models.ModerationAction) error {
 	query := `
-		INSERT INTO moderation_actions (admin_id, action_type, target_type, target_id, reason)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO moderation_actions (admin_id, action_type, target_type, target_id, reason, expires_at, details)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
 	`
 
-	var reasonPtr *string
-	if reason != "" {
-		reasonPtr = &reason
+	var reason, expiresAt, details interface{}
+	if action.Reason != nil && *action.Reason != "" {
+		reason = *action.Reason
+	}
+	if action.ExpiresAt != nil {
+		expiresAt = dbTime(*action.ExpiresAt)
+	}
+	if len(action.Details) > 0 {
+		details = string(action.Details)
 	}
 
-	_, err := db.conn.Exec(query, adminID, actionType, targetType, targetID, reasonPtr)
+	err := q.queryRow(query, action.AdminID, action.ActionType, action.TargetType, action.TargetID, reason, expiresAt, details).
+		Scan(&action.ID)
 	if err != nil {
 		return fmt.Errorf("failed to log moderation action: %w", err)
 	}
@@ -237,31 +255,51 @@ func (db *DB) GetAdminStats() (*models.AdminStats, error) {
 	stats := &models.AdminStats{}
 
 	// Get total users
-	err := db.conn.QueryRow("SELECT COUNT(*) FROM users").Scan(&stats.TotalUsers)
+	err := db.queryRow("SELECT COUNT(*) FROM users").Scan(&stats.TotalUsers)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, fmt.Errorf("failed to count users: %w", err)
 	}
 
 	// Get active users
-	err = db.conn.QueryRow("SELECT COUNT(*) FROM users WHERE is_active = 1").Scan(&stats.ActiveUsers)
+	err = db.queryRow("SELECT COUNT(*) FROM users WHERE is_active = TRUE").Scan(&stats.ActiveUsers)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, fmt.Errorf("failed to count active users: %w", err)
 	}
 
 	// Get total submissions
-	err = db.conn.QueryRow("SELECT COUNT(*) FROM naming_submissions").Scan(&stats.TotalSubmissions)
+	err = db.queryRow("SELECT COUNT(*) FROM naming_submissions WHERE deleted_at IS NULL").Scan(&stats.TotalSubmissions)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, fmt.Errorf("failed to count submissions: %w", err)
 	}
 
-	// Get total votes
-	err = db.conn.QueryRow("SELECT COUNT(*) FROM votes").Scan(&stats.TotalVotes)
+	// Get total votes on live submissions
+	err = db.queryRow(`
+		SELECT COUNT(*)
+		FROM votes v
+		JOIN naming_submissions ns ON ns.id = v.submission_id
+		WHERE ns.deleted_at IS NULL
+	`).Scan(&stats.TotalVotes)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, fmt.Errorf("failed to count votes: %w", err)
 	}
 
-	// Pending actions is always 0 for now (no approval workflow yet)
-	stats.PendingActions = 0
+	// Pending actions are submissions awaiting a moderator: those with open
+	// reports and those held for approval
+	var reported, held int
+	err = db.queryRow(`
+		SELECT COUNT(DISTINCT r.submission_id)
+		FROM submission_reports r
+		JOIN naming_submissions ns ON ns.id = r.submission_id
+		WHERE r.status = 'open' AND ns.deleted_at IS NULL
+	`).Scan(&reported)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to count reported submissions: %w", err)
+	}
+	err = db.queryRow("SELECT COUNT(*) FROM naming_submissions WHERE pending = TRUE AND deleted_at IS NULL").Scan(&held)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to count pending submissions: %w", err)
+	}
+	stats.PendingActions = reported + held
 
 	return stats, nil
 }
@@ -0,0 +1,388 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/quentinsteinke/mkvmender/internal/models"
+)
+
+const (
+	// apiKeyPrefix marks keys issued since keys are stored hashed
+	apiKeyPrefix = "mkv_"
+	// apiKeyLookupLength is how many leading characters of a key are stored
+	// in clear text to find its row
+	apiKeyLookupLength = 12
+	// apiKeyTouchInterval limits how often last_used_at is written
+	apiKeyTouchInterval = time.Minute
+	// placeholderAPIKeyPrefix marks users.api_key values that are not keys.
+	// The column is kept only because SQLite cannot drop a UNIQUE column.
+	placeholderAPIKeyPrefix = "hashed:"
+	// DefaultAPIKeyName names the key issued at registration
+	DefaultAPIKeyName = "default"
+)
+
+// CreateAPIKey issues a new named API key with the given scopes for a user.
+// The returned key is the only time the secret is available.
+func (db *DB) CreateAPIKey(userID int64, name string, scopes []models.Scope) (*models.APIKey, error) {
+	return createAPIKey(db, userID, name, scopes)
+}
+
+// createAPIKey issues a new API key using q, which may be a transaction
+func createAPIKey(q querier, userID int64, name string, scopes []models.Scope) (*models.APIKey, error) {
+	secret, err := generateAPIKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	salt, err := generateSalt()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, salt, scopes)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id, user_id, name, prefix, scopes, created_at
+	`
+
+	var key models.APIKey
+	var scopeList string
+	err = q.queryRow(query, userID, name, apiKeyLookup(secret), hashAPIKey(secret, salt), salt, encodeScopes(scopes)).Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&scopeList,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+	key.Scopes = decodeScopes(scopeList)
+	key.Key = secret
+
+	return &key, nil
+}
+
+// ListAPIKeys retrieves a user's API keys, oldest first
+func (db *DB) ListAPIKeys(userID int64) ([]models.APIKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, scopes, created_at, last_used_at
+		FROM api_keys
+		WHERE user_id = ?
+		ORDER BY created_at, id
+	`
+
+	rows, err := db.query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		var scopeList string
+		var lastUsed sql.NullTime
+		if err := rows.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &scopeList, &key.CreatedAt, &lastUsed); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		key.Scopes = decodeScopes(scopeList)
+		if lastUsed.Valid {
+			key.LastUsedAt = &lastUsed.Time
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return keys, nil
+}
+
+// DeleteAPIKey revokes one of a user's API keys
+func (db *DB) DeleteAPIKey(userID, keyID int64) error {
+	result, err := db.exec(`DELETE FROM api_keys WHERE id = ? AND user_id = ?`, keyID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete API key: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("api key not found")
+	}
+
+	return nil
+}
+
+// RevokeAPIKey revokes any user's API key by ID, returning the revoked key
+func (db *DB) RevokeAPIKey(keyID int64) (*models.APIKey, error) {
+	query := `
+		DELETE FROM api_keys
+		WHERE id = ?
+		RETURNING id, user_id, name, prefix, scopes, created_at
+	`
+
+	var key models.APIKey
+	var scopeList string
+	err := db.queryRow(query, keyID).Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&scopeList,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("api key not found")
+		}
+		return nil, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	key.Scopes = decodeScopes(scopeList)
+
+	return &key, nil
+}
+
+// RotateAPIKey replaces the secret of one of a user's API keys, keeping its
+// ID, name and scopes. The old secret stops working immediately.
+func (db *DB) RotateAPIKey(userID, keyID int64) (*models.APIKey, error) {
+	secret, err := generateAPIKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	salt, err := generateSalt()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	query := `
+		UPDATE api_keys
+		SET prefix = ?, key_hash = ?, salt = ?, created_at = CURRENT_TIMESTAMP, last_used_at = NULL
+		WHERE id = ? AND user_id = ?
+		RETURNING id, user_id, name, prefix, scopes, created_at
+	`
+
+	var key models.APIKey
+	var scopeList string
+	err = db.queryRow(query, apiKeyLookup(secret), hashAPIKey(secret, salt), salt, keyID, userID).Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&scopeList,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("api key not found")
+		}
+		return nil, fmt.Errorf("failed to rotate API key: %w", err)
+	}
+	key.Scopes = decodeScopes(scopeList)
+	key.Key = secret
+
+	return &key, nil
+}
+
+// AuthenticateAPIKey resolves a raw API key to its user and key record,
+// recording when the key was last used
+func (db *DB) AuthenticateAPIKey(apiKey string) (*models.User, *models.APIKey, error) {
+	if len(apiKey) < apiKeyLookupLength {
+		return nil, nil, fmt.Errorf("invalid API key")
+	}
+
+	query := `
+		SELECT
+			k.id, k.user_id, k.name, k.prefix, k.scopes, k.key_hash, k.salt, k.created_at, k.last_used_at,
+			u.id, u.username, u.role, u.is_active, u.suspended_until, u.reputation, u.trust_level, u.created_at, u.updated_at
+		FROM api_keys k
+		JOIN users u ON k.user_id = u.id
+		WHERE k.prefix = ?
+	`
+
+	rows, err := db.query(query, apiKeyLookup(apiKey))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var user *models.User
+	var key *models.APIKey
+	for rows.Next() {
+		var k models.APIKey
+		var u models.User
+		var scopeList, keyHash, salt string
+		var lastUsed, suspendedUntil sql.NullTime
+		err := rows.Scan(
+			&k.ID, &k.UserID, &k.Name, &k.Prefix, &scopeList, &keyHash, &salt, &k.CreatedAt, &lastUsed,
+			&u.ID, &u.Username, &u.Role, &u.IsActive, &suspendedUntil, &u.Reputation, &u.TrustLevel, &u.CreatedAt, &u.UpdatedAt,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+
+		if subtle.ConstantTimeCompare([]byte(hashAPIKey(apiKey, salt)), []byte(keyHash)) == 1 {
+			if lastUsed.Valid {
+				k.LastUsedAt = &lastUsed.Time
+			}
+			if suspendedUntil.Valid {
+				u.SuspendedUntil = &suspendedUntil.Time
+			}
+			k.Scopes = decodeScopes(scopeList)
+			user, key = &u, &k
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("rows error: %w", err)
+	}
+	rows.Close()
+
+	if key == nil {
+		return nil, nil, fmt.Errorf("invalid API key")
+	}
+
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if _, err := db.exec(`UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP WHERE id = ?`, key.ID); err != nil {
+			return nil, nil, fmt.Errorf("failed to record API key use: %w", err)
+		}
+	}
+
+	return user, key, nil
+}
+
+// MigrateLegacyAPIKeys moves plaintext keys still stored in users.api_key
+// into api_keys as hashed keys named "default" with every scope, replacing
+// the column value with a placeholder. Existing keys keep working. It
+// returns how many keys were migrated.
+func (db *DB) MigrateLegacyAPIKeys() (int, error) {
+	rows, err := db.query(`SELECT id, api_key FROM users WHERE api_key NOT LIKE ?`, placeholderAPIKeyPrefix+"%")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query legacy API keys: %w", err)
+	}
+
+	type legacyKey struct {
+		userID int64
+		key    string
+	}
+	var legacy []legacyKey
+	for rows.Next() {
+		var k legacyKey
+		if err := rows.Scan(&k.userID, &k.key); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan legacy API key: %w", err)
+		}
+		legacy = append(legacy, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("rows error: %w", err)
+	}
+
+	for i, k := range legacy {
+		err := db.inTx(func(tx *dbTx) error {
+			salt, err := generateSalt()
+			if err != nil {
+				return fmt.Errorf("failed to generate salt: %w", err)
+			}
+			placeholder, err := generatePlaceholderAPIKey()
+			if err != nil {
+				return fmt.Errorf("failed to generate placeholder: %w", err)
+			}
+
+			_, err = tx.exec(`
+				INSERT INTO api_keys (user_id, name, prefix, key_hash, salt, scopes)
+				VALUES (?, ?, ?, ?, ?, ?)
+			`, k.userID, DefaultAPIKeyName, apiKeyLookup(k.key), hashAPIKey(k.key, salt), salt, encodeScopes(models.AllScopes))
+			if err != nil {
+				return fmt.Errorf("failed to store hashed API key: %w", err)
+			}
+
+			_, err = tx.exec(`UPDATE users SET api_key = ? WHERE id = ?`, placeholder, k.userID)
+			if err != nil {
+				return fmt.Errorf("failed to clear legacy API key: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return i, fmt.Errorf("failed to migrate API key for user %d: %w", k.userID, err)
+		}
+	}
+
+	return len(legacy), nil
+}
+
+// encodeScopes stores scopes as a comma-separated list
+func encodeScopes(scopes []models.Scope) string {
+	parts := make([]string, len(scopes))
+	for i, scope := range scopes {
+		parts[i] = string(scope)
+	}
+	return strings.Join(parts, ",")
+}
+
+// decodeScopes parses a comma-separated scope list
+func decodeScopes(value string) []models.Scope {
+	scopes := []models.Scope{}
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			scopes = append(scopes, models.Scope(part))
+		}
+	}
+	return scopes
+}
+
+// apiKeyLookup returns the clear-text part of a key used to find its row
+func apiKeyLookup(apiKey string) string {
+	if len(apiKey) < apiKeyLookupLength {
+		return apiKey
+	}
+	return apiKey[:apiKeyLookupLength]
+}
+
+// hashAPIKey returns the hex SHA-256 of salt and key. Keys are long random
+// strings, so a fast salted hash is sufficient.
+func hashAPIKey(apiKey, salt string) string {
+	sum := sha256.Sum256([]byte(salt + apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateAPIKey generates a random API key of the form mkv_<40 hex chars>
+func generateAPIKey() (string, error) {
+	bytes := make([]byte, 20)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + hex.EncodeToString(bytes), nil
+}
+
+// generateSalt generates a random 16-byte salt
+func generateSalt() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// generatePlaceholderAPIKey generates a unique value for users.api_key that
+// can never match a real key
+func generatePlaceholderAPIKey() (string, error) {
+	salt, err := generateSalt()
+	if err != nil {
+		return "", err
+	}
+	return placeholderAPIKeyPrefix + salt, nil
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/quentinsteinke/mkvmender/internal/models"
+)
+
+// BulkSubmissionFilter selects live submissions for BulkDeleteSubmissions.
+// Every field that is set must match.
+type BulkSubmissionFilter struct {
+	UserID  *int64   `json:"user_id,omitempty"`
+	Pattern string   `json:"pattern,omitempty"` // Filename with * and ? wildcards, ignoring case
+	Hashes  []string `json:"hashes,omitempty"`
+}
+
+// where builds the WHERE clause and arguments selecting the filter's
+// submissions from naming_submissions ns joined with file_hashes fh
+func (f BulkSubmissionFilter) where() (string, []interface{}) {
+	whereClause := "WHERE ns.deleted_at IS NULL"
+	args := []interface{}{}
+
+	if f.UserID != nil {
+		whereClause += " AND ns.user_id = ?"
+		args = append(args, *f.UserID)
+	}
+	if f.Pattern != "" {
+		whereClause += ` AND LOWER(ns.filename) LIKE ? ESCAPE '\'`
+		args = append(args, likePattern(strings.ToLower(f.Pattern)))
+	}
+	if len(f.Hashes) > 0 {
+		placeholders := make([]string, len(f.Hashes))
+		for i, hash := range f.Hashes {
+			placeholders[i] = "?"
+			args = append(args, hash)
+		}
+		whereClause += " AND fh.hash IN (" + strings.Join(placeholders, ",") + ")"
+	}
+
+	return whereClause, args
+}
+
+// likePattern converts a pattern with * and ? wildcards to a LIKE pattern
+// escaped with a backslash
+func likePattern(pattern string) string {
+	var b strings.Builder
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteRune('%')
+		case '?':
+			b.WriteRune('_')
+		case '%', '_', '\\':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// BulkDeleteSubmissions deletes every live submission matching filter in
+// one transaction and records it as action, with the filter and deleted
+// IDs as its details. Each deleted submission is linked to the action so
+// its timeline shows it. With dryRun set it only reports what would be
+// deleted and logs nothing.
+func (db *DB) BulkDeleteSubmissions(filter BulkSubmissionFilter, deletedBy int64, dryRun bool, action *models.ModerationAction) (*models.BulkModerationResponse, error) {
+	result := &models.BulkModerationResponse{DryRun: dryRun, SubmissionIDs: []int64{}}
+	whereClause, args := filter.where()
+
+	err := db.inTx(func(tx *dbTx) error {
+		var query string
+		if dryRun {
+			query = `
+				SELECT ns.id
+				FROM naming_submissions ns
+				JOIN file_hashes fh ON fh.id = ns.hash_id
+				` + whereClause + `
+				ORDER BY ns.id
+			`
+		} else {
+			query = `
+				UPDATE naming_submissions
+				SET deleted_at = ?, deleted_by = ?
+				WHERE id IN (
+					SELECT ns.id
+					FROM naming_submissions ns
+					JOIN file_hashes fh ON fh.id = ns.hash_id
+					` + whereClause + `
+				)
+				RETURNING id
+			`
+			args = append([]interface{}{dbTime(time.Now()), deletedBy}, args...)
+		}
+
+		rows, err := tx.query(query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to delete submissions: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return fmt.Errorf("failed to scan submission ID: %w", err)
+			}
+			result.SubmissionIDs = append(result.SubmissionIDs, id)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("rows error: %w", err)
+		}
+		rows.Close()
+		result.Submissions = len(result.SubmissionIDs)
+
+		if dryRun {
+			return nil
+		}
+
+		details, err := json.Marshal(struct {
+			BulkSubmissionFilter
+			SubmissionIDs []int64 `json:"submission_ids"`
+		}{filter, result.SubmissionIDs})
+		if err != nil {
+			return fmt.Errorf("failed to encode action details: %w", err)
+		}
+		action.Details = details
+
+		if err := logModerationAction(tx, action); err != nil {
+			return err
+		}
+		result.ActionID = action.ID
+
+		for _, id := range result.SubmissionIDs {
+			_, err := tx.exec(`
+				INSERT INTO moderation_action_targets (action_id, target_type, target_id)
+				VALUES (?, ?, ?)
+			`, action.ID, models.TargetSubmission, id)
+			if err != nil {
+				return fmt.Errorf("failed to link deleted submission: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SuspendUserAndPurgeVotes suspends a user until suspendedUntil (nil for
+// good) and deletes every vote they cast, in one transaction, recording it
+// as action. With dryRun set it only counts the votes and logs nothing.
+func (db *DB) SuspendUserAndPurgeVotes(userID int64, suspendedUntil *time.Time, dryRun bool, action *models.ModerationAction) (*models.BulkModerationResponse, error) {
+	result := &models.BulkModerationResponse{DryRun: dryRun, SubmissionIDs: []int64{}}
+
+	err := db.inTx(func(tx *dbTx) error {
+		if dryRun {
+			err := tx.queryRow(`SELECT COUNT(*) FROM votes WHERE user_id = ?`, userID).Scan(&result.Votes)
+			if err != nil {
+				return fmt.Errorf("failed to count votes: %w", err)
+			}
+			return nil
+		}
+
+		if err := updateUserStatus(tx, userID, false, suspendedUntil); err != nil {
+			return err
+		}
+
+		deleted, err := tx.exec(`DELETE FROM votes WHERE user_id = ?`, userID)
+		if err != nil {
+			return fmt.Errorf("failed to delete votes: %w", err)
+		}
+		votes, err := deleted.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		result.Votes = int(votes)
+
+		details, err := json.Marshal(struct {
+			Votes int `json:"votes"`
+		}{result.Votes})
+		if err != nil {
+			return fmt.Errorf("failed to encode action details: %w", err)
+		}
+		action.Details = details
+		action.ExpiresAt = suspendedUntil
+
+		if err := logModerationAction(tx, action); err != nil {
+			return err
+		}
+		result.ActionID = action.ID
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotDeleted is returned when restoring a submission that was not deleted
+var ErrNotDeleted = errors.New("submission is not deleted")
+
+// ErrMerged is returned when restoring a submission that was merged into
+// another
+var ErrMerged = errors.New("submission was merged into another and cannot be restored")
+
+// DeleteSubmission marks a submission deleted by deletedBy. It disappears
+// from lookups, searches and listings but keeps its votes and metadata until
+// PurgeDeletedSubmissions removes it, so it can be restored until then.
+func (db *DB) DeleteSubmission(submissionID, deletedBy int64) error {
+	return deleteSubmission(db, submissionID, deletedBy)
+}
+
+// deleteSubmission marks a submission deleted, inside or outside a transaction
+func deleteSubmission(q querier, submissionID, deletedBy int64) error {
+	result, err := q.exec(`
+		UPDATE naming_submissions
+		SET deleted_at = ?, deleted_by = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`, dbTime(time.Now()), deletedBy, submissionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete submission: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("submission not found")
+	}
+
+	return nil
+}
+
+// RestoreSubmission undoes DeleteSubmission. Submissions deleted by
+// MergeSubmissions cannot be restored: their uploader's upvote and their
+// votes were copied to the merge target, and would count twice.
+func (db *DB) RestoreSubmission(submissionID int64) error {
+	result, err := db.exec(`
+		UPDATE naming_submissions
+		SET deleted_at = NULL, deleted_by = NULL
+		WHERE id = ? AND deleted_at IS NOT NULL AND merged_into IS NULL
+	`, submissionID)
+	if err != nil {
+		return fmt.Errorf("failed to restore submission: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		var deleted, merged bool
+		err := db.queryRow(`
+			SELECT deleted_at IS NOT NULL, merged_into IS NOT NULL
+			FROM naming_submissions
+			WHERE id = ?
+		`, submissionID).Scan(&deleted, &merged)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("submission not found")
+			}
+			return fmt.Errorf("failed to get submission: %w", err)
+		}
+		if deleted && merged {
+			return ErrMerged
+		}
+		return ErrNotDeleted
+	}
+
+	return nil
+}
+
+// PurgeDeletedSubmissions permanently removes submissions deleted more than
+// retention ago, along with their votes and metadata. It returns how many
+// submissions were removed.
+func (db *DB) PurgeDeletedSubmissions(retention time.Duration) (int, error) {
+	// Foreign keys with CASCADE will handle deletion of votes and metadata
+	result, err := db.exec(`
+		DELETE FROM naming_submissions
+		WHERE deleted_at IS NOT NULL AND deleted_at < ?
+	`, dbTime(time.Now().Add(-retention)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted submissions: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rows), nil
+}
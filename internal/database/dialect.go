@@ -0,0 +1,76 @@
+package database
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect identifies the SQL flavour spoken by the database
+type Dialect string
+
+const (
+	DialectSQLite   Dialect = "sqlite"   // Turso/libSQL and embedded SQLite
+	DialectPostgres Dialect = "postgres" // PostgreSQL
+)
+
+// Dialect returns the SQL dialect of the connected database
+func (db *DB) Dialect() Dialect {
+	return db.dialect
+}
+
+// rebind rewrites the ? placeholders used throughout this package into the
+// placeholder style of the connected database
+func (db *DB) rebind(query string) string {
+	if db.dialect != DialectPostgres || !strings.Contains(query, "?") {
+		return query
+	}
+	return rebindDollar(query)
+}
+
+// rebindDollar replaces ? placeholders with $1, $2, ... leaving question
+// marks inside quoted strings and identifiers alone
+func rebindDollar(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+// timestampLayout matches SQLite's CURRENT_TIMESTAMP and is accepted as a
+// timestamp literal by PostgreSQL
+const timestampLayout = "2006-01-02 15:04:05"
+
+// dbTime formats t for comparison against or storage in timestamp columns.
+// Times are stored in UTC without a zone so they order correctly against
+// CURRENT_TIMESTAMP defaults as plain strings in SQLite.
+func dbTime(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
+// isUniqueViolation reports whether err was caused by a UNIQUE constraint.
+// Drivers do not share an error type, so the message is inspected.
+func isUniqueViolation(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unique constraint") || strings.Contains(msg, "duplicate key")
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/quentinsteinke/mkvmender/internal/models"
+	"github.com/quentinsteinke/mkvmender/internal/naming"
+)
+
+// ErrDifferentFiles is returned when submissions to be merged are not for
+// the same file hash
+var ErrDifferentFiles = errors.New("submissions are for different files")
+
+// FindDuplicateSubmission returns the submission for hashID whose filename
+// matches filename exactly or, failing that, differs only in case, spacing
+// and punctuation. Submissions held for approval or hidden by reports only
+// match for their own uploader, userID, so they neither reveal what
+// moderation holds back nor absorb other users' uploads. excludeID, when
+// non-zero, is a submission to leave out, such as one being renamed. It
+// returns nil when there is none.
+func (db *DB) FindDuplicateSubmission(hashID int64, filename string, userID, excludeID int64) (*models.NamingSubmission, error) {
+	query := `
+		SELECT id, hash_id, user_id, filename, pending, created_at, updated_at
+		FROM naming_submissions
+		WHERE hash_id = ? AND deleted_at IS NULL AND id != ?
+		  AND ((pending = FALSE AND hidden = FALSE) OR user_id = ?)
+		ORDER BY created_at, id
+	`
+
+	rows, err := db.query(query, hashID, excludeID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query submissions: %w", err)
+	}
+	defer rows.Close()
+
+	var similar *models.NamingSubmission
+	for rows.Next() {
+		var s models.NamingSubmission
+		err := rows.Scan(
+			&s.ID,
+			&s.HashID,
+			&s.UserID,
+			&s.Filename,
+			&s.Pending,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan submission: %w", err)
+		}
+
+		if s.Filename == filename {
+			return &s, nil
+		}
+		if similar == nil && naming.Same(s.Filename, filename) {
+			similar = &s
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return similar, nil
+}
+
+// GetDuplicateSubmissions retrieves groups of submissions for the same hash
+// whose names differ only in case, spacing and punctuation. Each group is
+// ordered by score, so the first submission is the natural merge target.
+func (db *DB) GetDuplicateSubmissions() ([]models.DuplicateGroup, error) {
+	query := `
+		SELECT ` + submissionColumns + `
+		FROM submissions_with_votes
+		WHERE hash_id IN (
+			SELECT hash_id FROM naming_submissions
+			WHERE deleted_at IS NULL
+			GROUP BY hash_id
+			HAVING COUNT(*) > 1
+		)
+		ORDER BY hash_id, vote_score DESC, created_at, id
+	`
+
+	rows, err := db.query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query submissions: %w", err)
+	}
+	defer rows.Close()
+
+	type groupKey struct {
+		hashID int64
+		name   string
+	}
+	var order []groupKey
+	groups := make(map[groupKey]*models.DuplicateGroup)
+
+	for rows.Next() {
+		s, err := scanSubmission(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		key := groupKey{s.HashID, naming.Key(s.Filename)}
+		group, ok := groups[key]
+		if !ok {
+			group = &models.DuplicateGroup{Hash: s.Hash}
+			groups[key] = group
+			order = append(order, key)
+		}
+		group.Submissions = append(group.Submissions, *s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	duplicates := []models.DuplicateGroup{}
+	for _, key := range order {
+		if group := groups[key]; len(group.Submissions) > 1 {
+			duplicates = append(duplicates, *group)
+		}
+	}
+
+	return duplicates, nil
+}
+
+// MergeSubmissions folds the source submissions into targetID and deletes
+// them as deletedBy, recording the target in merged_into; RestoreSubmission
+// refuses to bring them back, as their votes now count on the target.
+// Each source's uploader is counted as an upvote for the target and the
+// sources' votes are copied across, still neutralized if they were, except
+// where the voter already voted on the target or uploaded it.
+func (db *DB) MergeSubmissions(targetID int64, sourceIDs []int64, deletedBy int64) error {
+	now := dbTime(time.Now())
+	return db.inTx(func(tx *dbTx) error {
+		var targetHashID, targetOwner int64
+		err := tx.queryRow(`SELECT hash_id, user_id FROM naming_submissions WHERE id = ? AND deleted_at IS NULL`, targetID).
+			Scan(&targetHashID, &targetOwner)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("submission not found")
+			}
+			return fmt.Errorf("failed to get submission: %w", err)
+		}
+
+		for _, sourceID := range sourceIDs {
+			var hashID, owner int64
+			var ownerTrust models.TrustLevel
+			err := tx.queryRow(`
+				SELECT ns.hash_id, ns.user_id, u.trust_level
+				FROM naming_submissions ns
+				JOIN users u ON u.id = ns.user_id
+				WHERE ns.id = ? AND ns.deleted_at IS NULL
+			`, sourceID).Scan(&hashID, &owner, &ownerTrust)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					return fmt.Errorf("submission not found")
+				}
+				return fmt.Errorf("failed to get submission: %w", err)
+			}
+			if hashID != targetHashID {
+				return ErrDifferentFiles
+			}
+
+			if owner != targetOwner {
+				_, err := tx.exec(`
+					INSERT INTO votes (submission_id, user_id, vote_type, weight)
+					VALUES (?, ?, ?, ?)
+					ON CONFLICT (submission_id, user_id) DO NOTHING
+				`, targetID, owner, int(models.VoteUp), ownerTrust.VoteWeight())
+				if err != nil {
+					return fmt.Errorf("failed to count uploader vote: %w", err)
+				}
+			}
+
+			_, err = tx.exec(`
+				INSERT INTO votes (submission_id, user_id, vote_type, weight, neutralized, created_at, updated_at)
+				SELECT ?, user_id, vote_type, weight, neutralized, created_at, updated_at
+				FROM votes
+				WHERE submission_id = ? AND user_id != ?
+				ON CONFLICT (submission_id, user_id) DO NOTHING
+			`, targetID, sourceID, targetOwner)
+			if err != nil {
+				return fmt.Errorf("failed to move votes: %w", err)
+			}
+
+			_, err = tx.exec(`
+				UPDATE naming_submissions
+				SET deleted_at = ?, deleted_by = ?, merged_into = ?
+				WHERE id = ?
+			`, now, deletedBy, targetID, sourceID)
+			if err != nil {
+				return fmt.Errorf("failed to delete submission: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
@@ -22,7 +22,7 @@ func (db *DB) CreateFileHash(hash string, fileSize int64, mediaType models.Media
 	`
 
 	var fileHash models.FileHash
-	err = db.conn.QueryRow(query, hash, fileSize, string(mediaType)).Scan(
+	err = db.queryRow(query, hash, fileSize, string(mediaType)).Scan(
 		&fileHash.ID,
 		&fileHash.Hash,
 		&fileHash.FileSize,
@@ -45,7 +45,7 @@ func (db *DB) GetFileHashByHash(hash string) (*models.FileHash, error) {
 	`
 
 	var fileHash models.FileHash
-	err := db.conn.QueryRow(query, hash).Scan(
+	err := db.queryRow(query, hash).Scan(
 		&fileHash.ID,
 		&fileHash.Hash,
 		&fileHash.FileSize,
@@ -71,7 +71,7 @@ func (db *DB) GetFileHashByID(id int64) (*models.FileHash, error) {
 	`
 
 	var fileHash models.FileHash
-	err := db.conn.QueryRow(query, id).Scan(
+	err := db.queryRow(query, id).Scan(
 		&fileHash.ID,
 		&fileHash.Hash,
 		&fileHash.FileSize,
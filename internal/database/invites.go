@@ -0,0 +1,139 @@
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/quentinsteinke/mkvmender/internal/models"
+)
+
+// ErrInvalidInvite is returned when an invite code does not exist, has
+// expired or has no uses left
+var ErrInvalidInvite = errors.New("invalid or expired invite code")
+
+// CreateInvite creates an invite code usable maxUses times, expiring at
+// expiresAt when it is non-nil
+func (db *DB) CreateInvite(createdBy int64, maxUses int, expiresAt *time.Time) (*models.Invite, error) {
+	code, err := generateInviteCode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate invite code: %w", err)
+	}
+
+	var expires interface{}
+	if expiresAt != nil {
+		expires = dbTime(*expiresAt)
+	}
+
+	query := `
+		INSERT INTO invite_codes (code, created_by, max_uses, expires_at)
+		VALUES (?, ?, ?, ?)
+		RETURNING ` + inviteColumns
+
+	return scanInvite(db.queryRow(query, code, createdBy, maxUses, expires))
+}
+
+// ListInvites retrieves all invite codes, newest first
+func (db *DB) ListInvites() ([]models.Invite, error) {
+	rows, err := db.query(`SELECT ` + inviteColumns + ` FROM invite_codes ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invites: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []models.Invite{}
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, *invite)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return invites, nil
+}
+
+// DeleteInvite revokes an invite code. Users who registered with it keep
+// their accounts.
+func (db *DB) DeleteInvite(inviteID int64) error {
+	result, err := db.exec(`DELETE FROM invite_codes WHERE id = ?`, inviteID)
+	if err != nil {
+		return fmt.Errorf("failed to delete invite: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("invite not found")
+	}
+
+	return nil
+}
+
+// redeemInvite uses up one use of an invite code and returns its ID
+func redeemInvite(q querier, code string) (int64, error) {
+	query := `
+		UPDATE invite_codes
+		SET uses = uses + 1
+		WHERE code = ? AND uses < max_uses AND (expires_at IS NULL OR expires_at > ?)
+		RETURNING id
+	`
+
+	var id int64
+	if err := q.queryRow(query, code, dbTime(time.Now())).Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrInvalidInvite
+		}
+		return 0, fmt.Errorf("failed to redeem invite: %w", err)
+	}
+
+	return id, nil
+}
+
+// inviteColumns lists the invite_codes columns read by scanInvite
+const inviteColumns = `id, code, created_by, max_uses, uses, expires_at, created_at`
+
+// scanInvite scans a row selected with inviteColumns
+func scanInvite(row interface{ Scan(...interface{}) error }) (*models.Invite, error) {
+	var invite models.Invite
+	var expiresAt sql.NullTime
+	err := row.Scan(
+		&invite.ID,
+		&invite.Code,
+		&invite.CreatedBy,
+		&invite.MaxUses,
+		&invite.Uses,
+		&expiresAt,
+		&invite.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("invite not found")
+		}
+		return nil, fmt.Errorf("failed to scan invite: %w", err)
+	}
+	if expiresAt.Valid {
+		invite.ExpiresAt = &expiresAt.Time
+	}
+
+	return &invite, nil
+}
+
+// generateInviteCode generates a random 16-character invite code
+func generateInviteCode() (string, error) {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
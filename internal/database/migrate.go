@@ -0,0 +1,131 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MigrateDir applies every *.sql file in dir that has not been applied yet,
+// in lexical order, recording each one in the schema_migrations table. It
+// returns the names of the files it applied.
+//
+// PostgreSQL databases use their own migration set from the postgres/
+// subdirectory of dir.
+func (db *DB) MigrateDir(dir string) ([]string, error) {
+	if db.dialect == DialectPostgres {
+		dir = filepath.Join(dir, "postgres")
+	}
+
+	_, err := db.exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version TEXT PRIMARY KEY,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".sql") {
+			files = append(files, entry.Name())
+		}
+	}
+	sort.Strings(files)
+
+	var ran []string
+	for _, name := range files {
+		if applied[name] {
+			continue
+		}
+
+		migrationSQL, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return ran, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+
+		if err := db.applyMigration(name, string(migrationSQL)); err != nil {
+			return ran, fmt.Errorf("migration %s failed: %w", name, err)
+		}
+		ran = append(ran, name)
+	}
+
+	return ran, nil
+}
+
+// appliedMigrations returns the set of recorded migration versions
+func (db *DB) appliedMigrations() (map[string]bool, error) {
+	rows, err := db.query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
+
+// preTrackingMigrations are the migrations that servers ran on every start
+// before schema_migrations existed. Databases from that time already have
+// them applied, but not recorded.
+var preTrackingMigrations = map[string]bool{
+	"002_add_admin_features.sql": true,
+}
+
+// applyMigration runs a migration and records it in one transaction
+func (db *DB) applyMigration(version, migrationSQL string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if _, err := tx.Exec(migrationSQL); err != nil {
+		tx.Rollback()
+
+		// Databases created before migrations were tracked already contain
+		// these changes; record the migration instead of failing forever
+		if !preTrackingMigrations[version] || !isAlreadyApplied(err) {
+			return err
+		}
+		if _, err := db.exec(`INSERT INTO schema_migrations (version) VALUES (?)`, version); err != nil {
+			return fmt.Errorf("failed to record migration: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := tx.Exec(db.rebind(`INSERT INTO schema_migrations (version) VALUES (?)`), version); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// isAlreadyApplied reports whether a migration error means its schema change
+// is already present
+func isAlreadyApplied(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "duplicate column")
+}
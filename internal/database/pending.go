@@ -0,0 +1,78 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/quentinsteinke/mkvmender/internal/models"
+)
+
+// ErrNotPending is returned when approving a submission that is not
+// awaiting approval
+var ErrNotPending = errors.New("submission is not awaiting approval")
+
+// GetPendingSubmissions retrieves a paginated list of submissions awaiting
+// approval, oldest first so the queue is worked in order
+func (db *DB) GetPendingSubmissions(page, limit int) ([]models.SubmissionWithVotes, int, error) {
+	offset := (page - 1) * limit
+
+	var total int
+	err := db.queryRow(`SELECT COUNT(*) FROM naming_submissions WHERE pending = TRUE AND deleted_at IS NULL`).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count pending submissions: %w", err)
+	}
+
+	query := `
+		SELECT ` + submissionColumns + `
+		FROM submissions_with_votes
+		WHERE pending = TRUE
+		ORDER BY created_at, id
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := db.query(query, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query pending submissions: %w", err)
+	}
+	defer rows.Close()
+
+	submissions := []models.SubmissionWithVotes{}
+	for rows.Next() {
+		s, err := scanSubmission(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		submissions = append(submissions, *s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows error: %w", err)
+	}
+
+	return submissions, total, nil
+}
+
+// ApproveSubmission publishes a submission that was awaiting approval
+func (db *DB) ApproveSubmission(submissionID int64) error {
+	result, err := db.exec(`
+		UPDATE naming_submissions
+		SET pending = FALSE
+		WHERE id = ? AND pending = TRUE AND deleted_at IS NULL
+	`, submissionID)
+	if err != nil {
+		return fmt.Errorf("failed to approve submission: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		if _, err := db.GetSubmissionByID(submissionID); err != nil {
+			return err
+		}
+		return ErrNotPending
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// QueryObserver is called after every query with the name of the DB method
+// that issued it, how long the query took and any error it returned
+type QueryObserver func(method string, duration time.Duration, err error)
+
+// SetQueryObserver registers a callback that is invoked after every query
+func (db *DB) SetQueryObserver(observer QueryObserver) {
+	db.observer = observer
+}
+
+// query runs a query that returns rows. Queries throughout this package use
+// ? placeholders; they are rewritten for the connected dialect here.
+func (db *DB) query(query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := db.conn.Query(db.rebind(query), args...)
+	db.observe(start, err)
+	return rows, err
+}
+
+// queryRow runs a query that returns at most one row
+func (db *DB) queryRow(query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := db.conn.QueryRow(db.rebind(query), args...)
+	db.observe(start, row.Err())
+	return row
+}
+
+// exec runs a statement that returns no rows
+func (db *DB) exec(query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := db.conn.Exec(db.rebind(query), args...)
+	db.observe(start, err)
+	return result, err
+}
+
+// observe reports a finished query to the observer, attributing it to the
+// DB method two frames up the stack
+func (db *DB) observe(start time.Time, err error) {
+	if db.observer == nil {
+		return
+	}
+	db.observer(callerMethod(3), time.Since(start), err)
+}
+
+// callerMethod returns the bare method name of the function skip frames up
+func callerMethod(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		return "unknown"
+	}
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return "unknown"
+	}
+
+	// e.g. ".../internal/database.(*DB).GetUserByID" or "...(*DB).SearchByTitle.func1"
+	name := fn.Name()
+	if idx := strings.Index(name, "(*DB)."); idx >= 0 {
+		name = name[idx+len("(*DB)."):]
+		if dot := strings.Index(name, "."); dot >= 0 {
+			name = name[:dot]
+		}
+		return name
+	}
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return name
+}
+
+// querier is implemented by DB and dbTx so helpers can run either inside or
+// outside a transaction
+type querier interface {
+	query(query string, args ...interface{}) (*sql.Rows, error)
+	queryRow(query string, args ...interface{}) *sql.Row
+	exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// dbTx is a transaction with the same placeholder rewriting and query
+// observation as DB
+type dbTx struct {
+	db *DB
+	tx *sql.Tx
+}
+
+// inTx runs fn in a transaction, committing if it returns nil and rolling
+// back otherwise. fn must only use tx: on SQLite a second connection would
+// wait on the transaction's write lock.
+func (db *DB) inTx(fn func(tx *dbTx) error) error {
+	sqlTx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(&dbTx{db: db, tx: sqlTx}); err != nil {
+		sqlTx.Rollback()
+		return err
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// query runs a query that returns rows inside the transaction
+func (t *dbTx) query(query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := t.tx.Query(t.db.rebind(query), args...)
+	t.db.observe(start, err)
+	return rows, err
+}
+
+// queryRow runs a query that returns at most one row inside the transaction
+func (t *dbTx) queryRow(query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := t.tx.QueryRow(t.db.rebind(query), args...)
+	t.db.observe(start, row.Err())
+	return row
+}
+
+// exec runs a statement that returns no rows inside the transaction
+func (t *dbTx) exec(query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := t.tx.Exec(t.db.rebind(query), args...)
+	t.db.observe(start, err)
+	return result, err
+}
@@ -0,0 +1,242 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/quentinsteinke/mkvmender/internal/models"
+)
+
+// ErrAlreadyReported is returned when a user reports the same submission
+// twice
+var ErrAlreadyReported = errors.New("you have already reported this submission")
+
+// CreateReport files a user's report against a submission. When hideAfter
+// is positive and the submission now has that many open reports, it is
+// hidden from lookups and searches. It returns the report ID and whether
+// this report hid the submission.
+func (db *DB) CreateReport(submissionID, reporterID int64, reason models.ReportReason, details *string, hideAfter int) (int64, bool, error) {
+	var reportID int64
+	hidden := false
+
+	err := db.inTx(func(tx *dbTx) error {
+		err := tx.queryRow(`
+			INSERT INTO submission_reports (submission_id, reporter_id, reason, details)
+			VALUES (?, ?, ?, ?)
+			RETURNING id
+		`, submissionID, reporterID, string(reason), details).Scan(&reportID)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrAlreadyReported
+			}
+			return fmt.Errorf("failed to create report: %w", err)
+		}
+
+		if hideAfter <= 0 {
+			return nil
+		}
+
+		var open int
+		err = tx.queryRow(`
+			SELECT COUNT(*) FROM submission_reports
+			WHERE submission_id = ? AND status = ?
+		`, submissionID, models.ReportOpen).Scan(&open)
+		if err != nil {
+			return fmt.Errorf("failed to count reports: %w", err)
+		}
+		if open < hideAfter {
+			return nil
+		}
+
+		result, err := tx.exec(`UPDATE naming_submissions SET hidden = TRUE WHERE id = ? AND hidden = FALSE`, submissionID)
+		if err != nil {
+			return fmt.Errorf("failed to hide submission: %w", err)
+		}
+		if n, err := result.RowsAffected(); err == nil && n > 0 {
+			hidden = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	return reportID, hidden, nil
+}
+
+// reportColumns is selected from submission_reports r joined with the
+// submission (ns) and reporter (u), in the order scanReport expects
+const reportColumns = `r.id, r.submission_id, ns.filename, r.reporter_id, u.username, r.reason,
+	r.details, r.status, r.resolved_by, r.resolved_at, r.created_at`
+
+// scanReport scans a row selected with reportColumns
+func scanReport(row interface{ Scan(...interface{}) error }) (*models.SubmissionReport, error) {
+	var r models.SubmissionReport
+	var reason string
+	var details sql.NullString
+	var resolvedBy sql.NullInt64
+	var resolvedAt sql.NullTime
+	err := row.Scan(
+		&r.ID,
+		&r.SubmissionID,
+		&r.Filename,
+		&r.ReporterID,
+		&r.ReporterUsername,
+		&reason,
+		&details,
+		&r.Status,
+		&resolvedBy,
+		&resolvedAt,
+		&r.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	r.Reason = models.ReportReason(reason)
+	if details.Valid {
+		r.Details = &details.String
+	}
+	if resolvedBy.Valid {
+		r.ResolvedBy = &resolvedBy.Int64
+	}
+	if resolvedAt.Valid {
+		r.ResolvedAt = &resolvedAt.Time
+	}
+	return &r, nil
+}
+
+// GetReport retrieves a report by ID
+func (db *DB) GetReport(id int64) (*models.SubmissionReport, error) {
+	query := `
+		SELECT ` + reportColumns + `
+		FROM submission_reports r
+		JOIN naming_submissions ns ON ns.id = r.submission_id
+		JOIN users u ON u.id = r.reporter_id
+		WHERE r.id = ?
+	`
+
+	r, err := scanReport(db.queryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("report not found")
+		}
+		return nil, fmt.Errorf("failed to get report: %w", err)
+	}
+
+	return r, nil
+}
+
+// ListReports retrieves a paginated list of reports, oldest first so the
+// queue is worked in order, optionally only those with the given status
+func (db *DB) ListReports(status string, page, limit int) ([]models.SubmissionReport, int, error) {
+	offset := (page - 1) * limit
+
+	whereClause := "WHERE 1=1"
+	args := []interface{}{}
+	if status != "" {
+		whereClause += " AND r.status = ?"
+		args = append(args, status)
+	}
+
+	var total int
+	err := db.queryRow(`SELECT COUNT(*) FROM submission_reports r `+whereClause, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count reports: %w", err)
+	}
+
+	query := `
+		SELECT ` + reportColumns + `
+		FROM submission_reports r
+		JOIN naming_submissions ns ON ns.id = r.submission_id
+		JOIN users u ON u.id = r.reporter_id
+		` + whereClause + `
+		ORDER BY r.created_at, r.id
+		LIMIT ? OFFSET ?
+	`
+
+	args = append(args, limit, offset)
+	rows, err := db.query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []models.SubmissionReport{}
+	for rows.Next() {
+		r, err := scanReport(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan report: %w", err)
+		}
+		reports = append(reports, *r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows error: %w", err)
+	}
+
+	return reports, total, nil
+}
+
+// ResolveReports closes every open report on a submission with status
+// (resolved or dismissed) and shows the submission again if the reports
+// had hidden it. It returns how many reports were closed.
+func (db *DB) ResolveReports(submissionID, moderatorID int64, status string) (int, error) {
+	var closed int
+	err := db.inTx(func(tx *dbTx) error {
+		var err error
+		closed, err = resolveReports(tx, submissionID, moderatorID, status)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return closed, nil
+}
+
+// ResolveReportsAndDelete deletes a submission and resolves every open
+// report on it in one transaction, so a failed delete leaves the reports
+// open and the submission hidden. It returns how many reports were closed.
+func (db *DB) ResolveReportsAndDelete(submissionID, moderatorID int64) (int, error) {
+	var closed int
+	err := db.inTx(func(tx *dbTx) error {
+		if err := deleteSubmission(tx, submissionID, moderatorID); err != nil {
+			return err
+		}
+
+		var err error
+		closed, err = resolveReports(tx, submissionID, moderatorID, models.ReportResolved)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return closed, nil
+}
+
+// resolveReports closes a submission's open reports with status and
+// unhides it
+func resolveReports(tx *dbTx, submissionID, moderatorID int64, status string) (int, error) {
+	result, err := tx.exec(`
+		UPDATE submission_reports
+		SET status = ?, resolved_by = ?, resolved_at = ?
+		WHERE submission_id = ? AND status = ?
+	`, status, moderatorID, dbTime(time.Now()), submissionID, models.ReportOpen)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve reports: %w", err)
+	}
+	closed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if _, err := tx.exec(`UPDATE naming_submissions SET hidden = FALSE WHERE id = ?`, submissionID); err != nil {
+		return 0, fmt.Errorf("failed to unhide submission: %w", err)
+	}
+
+	return int(closed), nil
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/quentinsteinke/mkvmender/internal/models"
+)
+
+const (
+	// reputationPerVote is earned for each net upvote other users give a
+	// user's submissions (and lost for each net downvote)
+	reputationPerVote = 5
+	// reputationPerAgreement is earned for each vote that agrees with the
+	// other voters on a submission (and lost for each that disagrees)
+	reputationPerAgreement = 1
+	// consensusMinVotes is how many votes a submission needs, including the
+	// one being judged, before agreement with it counts
+	consensusMinVotes = 3
+)
+
+// RecalculateReputation recomputes every user's reputation and trust level
+// from the votes on their submissions and their agreement with consensus,
+// then reweights each user's votes for their trust level. It returns how
+// many users changed trust level.
+func (db *DB) RecalculateReputation() (int, error) {
+	reputation := make(map[int64]int)
+
+	// Net votes from other users on each user's live submissions.
+	// Neutralized votes are left out here and below.
+	received := `
+		SELECT ns.user_id, SUM(v.vote_type)
+		FROM votes v
+		JOIN naming_submissions ns ON ns.id = v.submission_id
+		WHERE v.user_id != ns.user_id AND v.neutralized = FALSE AND ns.deleted_at IS NULL
+		GROUP BY ns.user_id
+	`
+	if err := addReputation(db, reputation, reputationPerVote, received); err != nil {
+		return 0, err
+	}
+
+	// Votes that agree (+1) or disagree (-1) with the rest of the votes on
+	// the same submission
+	agreement := `
+		SELECT v.user_id, SUM(
+			CASE
+				WHEN (t.score - v.vote_type) * v.vote_type > 0 THEN 1
+				WHEN (t.score - v.vote_type) * v.vote_type < 0 THEN -1
+				ELSE 0
+			END
+		)
+		FROM votes v
+		JOIN (
+			SELECT submission_id, SUM(vote_type) AS score, COUNT(*) AS total
+			FROM votes
+			WHERE neutralized = FALSE
+			GROUP BY submission_id
+		) t ON t.submission_id = v.submission_id
+		WHERE t.total >= ? AND v.neutralized = FALSE
+		GROUP BY v.user_id
+	`
+	if err := addReputation(db, reputation, reputationPerAgreement, agreement, consensusMinVotes); err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	err := db.inTx(func(tx *dbTx) error {
+		rows, err := tx.query(`SELECT id, reputation, trust_level FROM users`)
+		if err != nil {
+			return fmt.Errorf("failed to query users: %w", err)
+		}
+
+		type update struct {
+			id         int64
+			reputation int
+			level      models.TrustLevel
+		}
+		var updates []update
+		for rows.Next() {
+			var id int64
+			var current int
+			var level models.TrustLevel
+			if err := rows.Scan(&id, &current, &level); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan user: %w", err)
+			}
+
+			rep := max(reputation[id], 0)
+			newLevel := models.TrustLevelFor(rep)
+			if rep != current || newLevel != level {
+				updates = append(updates, update{id, rep, newLevel})
+			}
+			if newLevel != level {
+				changed++
+			}
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return fmt.Errorf("rows error: %w", err)
+		}
+		rows.Close()
+
+		for _, u := range updates {
+			if _, err := tx.exec(`UPDATE users SET reputation = ?, trust_level = ? WHERE id = ?`,
+				u.reputation, int(u.level), u.id); err != nil {
+				return fmt.Errorf("failed to update reputation: %w", err)
+			}
+		}
+
+		for level, weight := range models.TrustVoteWeights {
+			_, err := tx.exec(`
+				UPDATE votes SET weight = ?
+				WHERE weight != ? AND user_id IN (SELECT id FROM users WHERE trust_level = ?)
+			`, weight, weight, int(level))
+			if err != nil {
+				return fmt.Errorf("failed to reweight votes: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return changed, nil
+}
+
+// addReputation runs query, which selects a user ID and a count, and adds
+// the count times points to each user's reputation
+func addReputation(db *DB, reputation map[int64]int, points int, query string, args ...interface{}) error {
+	rows, err := db.query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to calculate reputation: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID int64
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return fmt.Errorf("failed to scan reputation: %w", err)
+		}
+		reputation[userID] += count * points
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/quentinsteinke/mkvmender/internal/models"
+)
+
+// revisionSnapshot selects a submission's current filename and metadata in
+// the column order of submission_revisions
+const revisionSnapshot = `
+	ns.filename, nm.title, nm.year, nm.season, nm.episode, nm.quality, nm.source
+	FROM naming_submissions ns
+	LEFT JOIN naming_metadata nm ON nm.submission_id = ns.id
+`
+
+// recordOriginalRevision stores a submission's current state as its first
+// revision, attributed to the uploader, unless it already has revisions
+func recordOriginalRevision(q querier, submissionID int64) error {
+	query := `
+		INSERT INTO submission_revisions
+			(submission_id, user_id, created_at, filename, title, year, season, episode, quality, source)
+		SELECT ns.id, ns.user_id, ns.created_at, ` + revisionSnapshot + `
+		WHERE ns.id = ? AND NOT EXISTS (
+			SELECT 1 FROM submission_revisions sr WHERE sr.submission_id = ns.id
+		)
+	`
+
+	if _, err := q.exec(query, submissionID); err != nil {
+		return fmt.Errorf("failed to record original revision: %w", err)
+	}
+
+	return nil
+}
+
+// recordRevision stores a submission's current state as a revision made by
+// userID
+func recordRevision(q querier, submissionID, userID int64, reason *string, votesReset bool) error {
+	query := `
+		INSERT INTO submission_revisions
+			(submission_id, user_id, reason, votes_reset, filename, title, year, season, episode, quality, source)
+		SELECT ns.id, ?, ?, ?, ` + revisionSnapshot + `
+		WHERE ns.id = ?
+	`
+
+	if _, err := q.exec(query, userID, reason, votesReset, submissionID); err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+
+	return nil
+}
+
+// RecordOriginalRevision stores a newly uploaded submission's filename and
+// metadata as its first revision
+func (db *DB) RecordOriginalRevision(submissionID int64) error {
+	return recordOriginalRevision(db, submissionID)
+}
+
+// GetSubmissionRevisions retrieves a submission's revisions, newest first
+func (db *DB) GetSubmissionRevisions(submissionID int64) ([]models.SubmissionRevision, error) {
+	query := `
+		SELECT
+			sr.id, sr.submission_id, sr.user_id, u.username, sr.filename,
+			sr.title, sr.year, sr.season, sr.episode, sr.quality, sr.source,
+			sr.reason, sr.votes_reset, sr.created_at
+		FROM submission_revisions sr
+		JOIN users u ON u.id = sr.user_id
+		WHERE sr.submission_id = ?
+		ORDER BY sr.created_at DESC, sr.id DESC
+	`
+
+	rows, err := db.query(query, submissionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.SubmissionRevision{}
+	for rows.Next() {
+		var rev models.SubmissionRevision
+		err := rows.Scan(
+			&rev.ID,
+			&rev.SubmissionID,
+			&rev.UserID,
+			&rev.Username,
+			&rev.Filename,
+			&rev.Title,
+			&rev.Year,
+			&rev.Season,
+			&rev.Episode,
+			&rev.Quality,
+			&rev.Source,
+			&rev.Reason,
+			&rev.VotesReset,
+			&rev.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan revision: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// RollbackSubmission restores a submission's filename and metadata to those
+// of one of its revisions and records the result as a new revision by
+// userID. Votes are kept.
+func (db *DB) RollbackSubmission(submissionID, revisionID, userID int64, reason *string) error {
+	return db.inTx(func(tx *dbTx) error {
+		if err := recordOriginalRevision(tx, submissionID); err != nil {
+			return err
+		}
+
+		var filename string
+		var meta models.NamingMetadata
+		err := tx.queryRow(`
+			SELECT filename, title, year, season, episode, quality, source
+			FROM submission_revisions
+			WHERE id = ? AND submission_id = ?
+		`, revisionID, submissionID).Scan(
+			&filename,
+			&meta.Title,
+			&meta.Year,
+			&meta.Season,
+			&meta.Episode,
+			&meta.Quality,
+			&meta.Source,
+		)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("revision not found")
+			}
+			return fmt.Errorf("failed to get revision: %w", err)
+		}
+
+		if _, err := tx.exec(`
+			UPDATE naming_submissions
+			SET filename = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, filename, submissionID); err != nil {
+			return fmt.Errorf("failed to update submission: %w", err)
+		}
+
+		if _, err := tx.exec(`
+			INSERT INTO naming_metadata (submission_id, title, year, season, episode, quality, source)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (submission_id) DO UPDATE SET
+				title = excluded.title,
+				year = excluded.year,
+				season = excluded.season,
+				episode = excluded.episode,
+				quality = excluded.quality,
+				source = excluded.source
+		`, submissionID, meta.Title, meta.Year, meta.Season, meta.Episode, meta.Quality, meta.Source); err != nil {
+			return fmt.Errorf("failed to update metadata: %w", err)
+		}
+
+		return recordRevision(tx, submissionID, userID, reason, false)
+	})
+}
@@ -50,7 +50,7 @@ func (db *DB) SearchByTitle(query string, sortBy SortBy, useFuzzy bool) ([]Searc
 		WHERE nm.title IS NOT NULL
 	`
 
-	rows, err := db.conn.Query(titlesQuery)
+	rows, err := db.query(titlesQuery)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get titles: %w", err)
 	}
@@ -109,11 +109,11 @@ func (db *DB) SearchByTitle(query string, sortBy SortBy, useFuzzy bool) ([]Searc
 		FROM naming_metadata nm
 		JOIN naming_submissions ns ON nm.submission_id = ns.id
 		JOIN file_hashes fh ON ns.hash_id = fh.id
-		WHERE nm.title IN (%s)
+		WHERE nm.title IN (%s) AND ns.hidden = FALSE AND ns.pending = FALSE AND ns.deleted_at IS NULL
 		ORDER BY nm.title, nm.year DESC, nm.season, nm.episode
 	`, strings.Join(placeholders, ","))
 
-	rows, err = db.conn.Query(sqlQuery, args...)
+	rows, err = db.query(sqlQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search: %w", err)
 	}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Defaults for embedded SQLite databases
+const (
+	defaultSQLiteBusyTimeout  = 5 * time.Second
+	defaultSQLiteMaxOpenConns = 4
+)
+
+// isSQLiteURL reports whether url refers to a local SQLite file
+func isSQLiteURL(url string) bool {
+	return strings.HasPrefix(url, "file:")
+}
+
+// openSQLite opens an embedded SQLite database.
+//
+// SQLite leaves foreign keys off by default, so they are enabled on every
+// connection; the ON DELETE CASCADE rules in the schema depend on it. WAL
+// mode lets readers run alongside the single writer, and transactions take
+// the write lock up front (_txlock=immediate) so concurrent writers wait out
+// busy_timeout instead of failing with SQLITE_BUSY mid-transaction.
+func openSQLite(cfg Config) (*sql.DB, error) {
+	dsn, path, err := sqliteDSN(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite creates the file but not its parent directory
+	if path != "" && path != ":memory:" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	maxOpenConns := cfg.MaxOpenConns
+	if maxOpenConns <= 0 {
+		maxOpenConns = defaultSQLiteMaxOpenConns
+	}
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxOpenConns)
+	// Connections are cheap to keep and carry per-connection pragmas
+	db.SetConnMaxLifetime(0)
+
+	return db, nil
+}
+
+// sqliteDSN builds a modernc.org/sqlite DSN from a file: URL, returning the
+// DSN and the database file path
+func sqliteDSN(cfg Config) (string, string, error) {
+	rawPath, rawQuery, _ := strings.Cut(strings.TrimPrefix(cfg.URL, "file:"), "?")
+	// Accept file:///abs/path as well as file:rel/path and file:/abs/path
+	path := rawPath
+	if strings.HasPrefix(path, "//") {
+		path = strings.TrimPrefix(path, "//")
+	}
+	if path == "" {
+		return "", "", fmt.Errorf("database URL %q has no file path", cfg.URL)
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid database URL query: %w", err)
+	}
+
+	busyTimeout := cfg.BusyTimeout
+	if busyTimeout <= 0 {
+		busyTimeout = defaultSQLiteBusyTimeout
+	}
+
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", "synchronous(NORMAL)")
+	query.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()))
+	if query.Get("_txlock") == "" {
+		query.Set("_txlock", "immediate")
+	}
+
+	return "file:" + path + "?" + query.Encode(), path, nil
+}
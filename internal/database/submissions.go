@@ -7,20 +7,22 @@ import (
 	"github.com/quentinsteinke/mkvmender/internal/models"
 )
 
-// CreateSubmission creates a new naming submission
-func (db *DB) CreateSubmission(hashID, userID int64, filename string) (*models.NamingSubmission, error) {
+// CreateSubmission creates a new naming submission. Pending submissions
+// stay out of lookups and searches until a moderator approves them.
+func (db *DB) CreateSubmission(hashID, userID int64, filename string, pending bool) (*models.NamingSubmission, error) {
 	query := `
-		INSERT INTO naming_submissions (hash_id, user_id, filename)
-		VALUES (?, ?, ?)
-		RETURNING id, hash_id, user_id, filename, created_at, updated_at
+		INSERT INTO naming_submissions (hash_id, user_id, filename, pending)
+		VALUES (?, ?, ?, ?)
+		RETURNING id, hash_id, user_id, filename, pending, created_at, updated_at
 	`
 
 	var submission models.NamingSubmission
-	err := db.conn.QueryRow(query, hashID, userID, filename).Scan(
+	err := db.queryRow(query, hashID, userID, filename, pending).Scan(
 		&submission.ID,
 		&submission.HashID,
 		&submission.UserID,
 		&submission.Filename,
+		&submission.Pending,
 		&submission.CreatedAt,
 		&submission.UpdatedAt,
 	)
@@ -31,49 +33,29 @@ func (db *DB) CreateSubmission(hashID, userID int64, filename string) (*models.N
 	return &submission, nil
 }
 
-// GetSubmissionsByHash retrieves all naming submissions for a given hash with vote counts
+// GetSubmissionsByHash retrieves the visible naming submissions for a given
+// hash with vote counts
 func (db *DB) GetSubmissionsByHash(hash string) ([]models.SubmissionWithVotes, error) {
 	query := `
-		SELECT
-			id, hash_id, user_id, filename, created_at,
-			hash, file_size, media_type, username,
-			vote_score, upvotes, downvotes
+		SELECT ` + submissionColumns + `
 		FROM submissions_with_votes
-		WHERE hash = ?
+		WHERE hash = ? AND hidden = FALSE AND pending = FALSE
 		ORDER BY vote_score DESC, created_at DESC
 	`
 
-	rows, err := db.conn.Query(query, hash)
+	rows, err := db.query(query, hash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query submissions: %w", err)
 	}
 	defer rows.Close()
 
-	var submissions []models.SubmissionWithVotes
+	submissions := []models.SubmissionWithVotes{}
 	for rows.Next() {
-		var s models.SubmissionWithVotes
-		var mediaTypeStr string
-
-		err := rows.Scan(
-			&s.ID,
-			&s.HashID,
-			&s.UserID,
-			&s.Filename,
-			&s.CreatedAt,
-			&s.Hash,
-			&s.FileSize,
-			&mediaTypeStr,
-			&s.Username,
-			&s.VoteScore,
-			&s.Upvotes,
-			&s.Downvotes,
-		)
+		s, err := scanSubmission(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan submission: %w", err)
+			return nil, err
 		}
-
-		s.MediaType = models.MediaType(mediaTypeStr)
-		submissions = append(submissions, s)
+		submissions = append(submissions, *s)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -86,43 +68,142 @@ func (db *DB) GetSubmissionsByHash(hash string) ([]models.SubmissionWithVotes, e
 // GetSubmissionByID retrieves a submission by its ID
 func (db *DB) GetSubmissionByID(id int64) (*models.SubmissionWithVotes, error) {
 	query := `
-		SELECT
-			id, hash_id, user_id, filename, created_at,
-			hash, file_size, media_type, username,
-			vote_score, upvotes, downvotes
+		SELECT ` + submissionColumns + `
 		FROM submissions_with_votes
 		WHERE id = ?
 	`
 
+	return scanSubmission(db.queryRow(query, id))
+}
+
+// GetSubmissionsByUser retrieves a user's submissions with vote counts,
+// newest first
+func (db *DB) GetSubmissionsByUser(userID int64) ([]models.SubmissionWithVotes, error) {
+	query := `
+		SELECT ` + submissionColumns + `
+		FROM submissions_with_votes
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := db.query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query submissions: %w", err)
+	}
+	defer rows.Close()
+
+	submissions := []models.SubmissionWithVotes{}
+	for rows.Next() {
+		s, err := scanSubmission(rows)
+		if err != nil {
+			return nil, err
+		}
+		submissions = append(submissions, *s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return submissions, nil
+}
+
+// submissionColumns lists the submissions_with_votes columns read by
+// scanSubmission
+const submissionColumns = `
+	id, hash_id, user_id, filename, created_at, hidden, pending,
+	hash, file_size, media_type, username, reputation, trust_level,
+	vote_score, upvotes, downvotes, weighted_upvotes, weighted_downvotes
+`
+
+// scanSubmission scans a row selected with submissionColumns
+func scanSubmission(row interface{ Scan(...interface{}) error }) (*models.SubmissionWithVotes, error) {
 	var s models.SubmissionWithVotes
 	var mediaTypeStr string
-
-	err := db.conn.QueryRow(query, id).Scan(
+	err := row.Scan(
 		&s.ID,
 		&s.HashID,
 		&s.UserID,
 		&s.Filename,
 		&s.CreatedAt,
+		&s.Hidden,
+		&s.Pending,
 		&s.Hash,
 		&s.FileSize,
 		&mediaTypeStr,
 		&s.Username,
+		&s.UserReputation,
+		&s.UserTrustLevel,
 		&s.VoteScore,
 		&s.Upvotes,
 		&s.Downvotes,
+		&s.WeightedUpvotes,
+		&s.WeightedDownvotes,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("submission not found")
 		}
-		return nil, fmt.Errorf("failed to get submission: %w", err)
+		return nil, fmt.Errorf("failed to scan submission: %w", err)
 	}
-
 	s.MediaType = models.MediaType(mediaTypeStr)
 
 	return &s, nil
 }
 
+// UpdateSubmission renames a submission and merges meta into its metadata
+// (nil fields keep their values), recording the result as a revision by
+// userID. The state before the first edit is kept as the original
+// revision. When resetVotes is set, the submission's votes are removed.
+func (db *DB) UpdateSubmission(submissionID, userID int64, filename string, meta *models.NamingMetadata, reason *string, resetVotes bool) error {
+	return db.inTx(func(tx *dbTx) error {
+		if err := recordOriginalRevision(tx, submissionID); err != nil {
+			return err
+		}
+
+		result, err := tx.exec(`
+			UPDATE naming_submissions
+			SET filename = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, filename, submissionID)
+		if err != nil {
+			return fmt.Errorf("failed to update submission: %w", err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows == 0 {
+			return fmt.Errorf("submission not found")
+		}
+
+		if meta != nil {
+			_, err := tx.exec(`
+				INSERT INTO naming_metadata (submission_id, title, year, season, episode, quality, source)
+				VALUES (?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT (submission_id) DO UPDATE SET
+					title = COALESCE(excluded.title, naming_metadata.title),
+					year = COALESCE(excluded.year, naming_metadata.year),
+					season = COALESCE(excluded.season, naming_metadata.season),
+					episode = COALESCE(excluded.episode, naming_metadata.episode),
+					quality = COALESCE(excluded.quality, naming_metadata.quality),
+					source = COALESCE(excluded.source, naming_metadata.source)
+			`, submissionID, meta.Title, meta.Year, meta.Season, meta.Episode, meta.Quality, meta.Source)
+			if err != nil {
+				return fmt.Errorf("failed to update metadata: %w", err)
+			}
+		}
+
+		if resetVotes {
+			if _, err := tx.exec(`DELETE FROM votes WHERE submission_id = ?`, submissionID); err != nil {
+				return fmt.Errorf("failed to reset votes: %w", err)
+			}
+		}
+
+		return recordRevision(tx, submissionID, userID, reason, resetVotes)
+	})
+}
+
 // CreateMetadata creates naming metadata for a submission
 func (db *DB) CreateMetadata(meta *models.NamingMetadata) error {
 	query := `
@@ -130,7 +211,7 @@ func (db *DB) CreateMetadata(meta *models.NamingMetadata) error {
 		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := db.conn.Exec(query,
+	_, err := db.exec(query,
 		meta.SubmissionID,
 		meta.Title,
 		meta.Year,
@@ -155,7 +236,7 @@ func (db *DB) GetMetadataBySubmissionID(submissionID int64) (*models.NamingMetad
 	`
 
 	var meta models.NamingMetadata
-	err := db.conn.QueryRow(query, submissionID).Scan(
+	err := db.queryRow(query, submissionID).Scan(
 		&meta.ID,
 		&meta.SubmissionID,
 		&meta.Title,
@@ -1,88 +1,144 @@
 package database
 
 import (
-	"crypto/rand"
 	"database/sql"
-	"encoding/hex"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/quentinsteinke/mkvmender/internal/models"
+	"github.com/quentinsteinke/mkvmender/internal/username"
 )
 
-// CreateUser creates a new user with a generated API key
-func (db *DB) CreateUser(username string) (*models.User, error) {
-	// Generate random API key
-	apiKey, err := generateAPIKey()
+// ErrUsernameTaken is returned by CreateUser when the username, or one that
+// looks the same, is already registered
+var ErrUsernameTaken = errors.New("username is already taken")
+
+// CreateUser creates a new user with a default API key holding the default
+// scopes, redeeming inviteCode when it is non-empty. The returned user
+// carries the key's secret in APIKey; it is not retrievable afterwards.
+func (db *DB) CreateUser(name, inviteCode string) (*models.User, error) {
+	placeholder, err := generatePlaceholderAPIKey()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate API key: %w", err)
 	}
+	canonical := username.Canonical(name)
 
 	query := `
-		INSERT INTO users (username, api_key, role, is_active)
-		VALUES (?, ?, 'user', 1)
-		RETURNING id, username, api_key, role, is_active, created_at, updated_at
+		INSERT INTO users (username, username_canonical, api_key, role, is_active, invite_code_id)
+		VALUES (?, ?, ?, 'user', TRUE, ?)
+		RETURNING id, username, role, is_active, created_at, updated_at
 	`
 
 	var user models.User
-	err = db.conn.QueryRow(query, username, apiKey).Scan(
-		&user.ID,
-		&user.Username,
-		&user.APIKey,
-		&user.Role,
-		&user.IsActive,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	err = db.inTx(func(tx *dbTx) error {
+		var taken bool
+		err := tx.queryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE username_canonical = ? OR username = ?)`, canonical, name).Scan(&taken)
+		if err != nil {
+			return fmt.Errorf("failed to check username: %w", err)
+		}
+		if taken {
+			return ErrUsernameTaken
+		}
+
+		var inviteID interface{}
+		if inviteCode != "" {
+			id, err := redeemInvite(tx, inviteCode)
+			if err != nil {
+				return err
+			}
+			inviteID = id
+		}
+
+		err = tx.queryRow(query, name, canonical, placeholder, inviteID).Scan(
+			&user.ID,
+			&user.Username,
+			&user.Role,
+			&user.IsActive,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+		)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrUsernameTaken
+			}
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+
+		key, err := createAPIKey(tx, user.ID, DefaultAPIKeyName, models.DefaultScopes)
+		if err != nil {
+			return err
+		}
+		user.APIKey = key.Key
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+		return nil, err
 	}
 
 	return &user, nil
 }
 
-// GetUserByAPIKey retrieves a user by their API key
-func (db *DB) GetUserByAPIKey(apiKey string) (*models.User, error) {
-	query := `
-		SELECT id, username, api_key, role, is_active, created_at, updated_at
-		FROM users
-		WHERE api_key = ?
-	`
-
-	var user models.User
-	err := db.conn.QueryRow(query, apiKey).Scan(
-		&user.ID,
-		&user.Username,
-		&user.APIKey,
-		&user.Role,
-		&user.IsActive,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+// BackfillUsernameCanonical computes username_canonical for users created
+// before it existed. Users whose canonical form collides with an earlier
+// account keep a NULL value and are returned by username so an admin can
+// follow up; new registrations still cannot take their name.
+func (db *DB) BackfillUsernameCanonical() (int, []string, error) {
+	rows, err := db.query(`SELECT id, username FROM users WHERE username_canonical IS NULL ORDER BY id`)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+		return 0, nil, fmt.Errorf("failed to query usernames: %w", err)
+	}
+
+	type pending struct {
+		id   int64
+		name string
+	}
+	var users []pending
+	for rows.Next() {
+		var u pending
+		if err := rows.Scan(&u.id, &u.name); err != nil {
+			rows.Close()
+			return 0, nil, fmt.Errorf("failed to scan username: %w", err)
 		}
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		users = append(users, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("rows error: %w", err)
 	}
 
-	return &user, nil
+	updated := 0
+	var collisions []string
+	for _, u := range users {
+		_, err := db.exec(`UPDATE users SET username_canonical = ? WHERE id = ?`, username.Canonical(u.name), u.id)
+		if err != nil {
+			if isUniqueViolation(err) {
+				collisions = append(collisions, u.name)
+				continue
+			}
+			return updated, collisions, fmt.Errorf("failed to update user %d: %w", u.id, err)
+		}
+		updated++
+	}
+
+	return updated, collisions, nil
 }
 
-// GetUserByUsername retrieves a user by their username
-func (db *DB) GetUserByUsername(username string) (*models.User, error) {
-	query := `
-		SELECT id, username, api_key, role, is_active, created_at, updated_at
-		FROM users
-		WHERE username = ?
-	`
+// userColumns lists the users columns read by scanUser
+const userColumns = `id, username, role, is_active, suspended_until, reputation, trust_level, created_at, updated_at`
 
+// scanUser scans a row selected with userColumns
+func scanUser(row *sql.Row) (*models.User, error) {
 	var user models.User
-	err := db.conn.QueryRow(query, username).Scan(
+	var suspendedUntil sql.NullTime
+	err := row.Scan(
 		&user.ID,
 		&user.Username,
-		&user.APIKey,
 		&user.Role,
 		&user.IsActive,
+		&suspendedUntil,
+		&user.Reputation,
+		&user.TrustLevel,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -92,36 +148,23 @@ func (db *DB) GetUserByUsername(username string) (*models.User, error) {
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+	if suspendedUntil.Valid {
+		user.SuspendedUntil = &suspendedUntil.Time
+	}
 
 	return &user, nil
 }
 
+// GetUserByUsername retrieves a user by their username
+func (db *DB) GetUserByUsername(username string) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = ?`
+	return scanUser(db.queryRow(query, username))
+}
+
 // GetUserByID retrieves a user by their ID
 func (db *DB) GetUserByID(userID int64) (*models.User, error) {
-	query := `
-		SELECT id, username, api_key, role, is_active, created_at, updated_at
-		FROM users
-		WHERE id = ?
-	`
-
-	var user models.User
-	err := db.conn.QueryRow(query, userID).Scan(
-		&user.ID,
-		&user.Username,
-		&user.APIKey,
-		&user.Role,
-		&user.IsActive,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
-		}
-		return nil, fmt.Errorf("failed to get user: %w", err)
-	}
-
-	return &user, nil
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ?`
+	return scanUser(db.queryRow(query, userID))
 }
 
 // UpdateUserRole updates a user's role
@@ -132,7 +175,7 @@ func (db *DB) UpdateUserRole(userID int64, role models.UserRole) error {
 		WHERE id = ?
 	`
 
-	result, err := db.conn.Exec(query, role, userID)
+	result, err := db.exec(query, role, userID)
 	if err != nil {
 		return fmt.Errorf("failed to update user role: %w", err)
 	}
@@ -149,15 +192,26 @@ func (db *DB) UpdateUserRole(userID int64, role models.UserRole) error {
 	return nil
 }
 
-// UpdateUserStatus updates a user's active status
-func (db *DB) UpdateUserStatus(userID int64, isActive bool) error {
+// UpdateUserStatus activates or suspends a user. A suspension ends
+// automatically at suspendedUntil when it is non-nil; activating clears it.
+func (db *DB) UpdateUserStatus(userID int64, isActive bool, suspendedUntil *time.Time) error {
+	return updateUserStatus(db, userID, isActive, suspendedUntil)
+}
+
+// updateUserStatus runs UpdateUserStatus with q
+func updateUserStatus(q querier, userID int64, isActive bool, suspendedUntil *time.Time) error {
 	query := `
 		UPDATE users
-		SET is_active = ?, updated_at = CURRENT_TIMESTAMP
+		SET is_active = ?, suspended_until = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
 	`
 
-	result, err := db.conn.Exec(query, isActive, userID)
+	var until interface{}
+	if !isActive && suspendedUntil != nil {
+		until = dbTime(*suspendedUntil)
+	}
+
+	result, err := q.exec(query, isActive, until, userID)
 	if err != nil {
 		return fmt.Errorf("failed to update user status: %w", err)
 	}
@@ -174,11 +228,46 @@ func (db *DB) UpdateUserStatus(userID int64, isActive bool) error {
 	return nil
 }
 
-// generateAPIKey generates a random 32-byte API key
-func generateAPIKey() (string, error) {
-	bytes := make([]byte, 32)
-	if _, err := rand.Read(bytes); err != nil {
-		return "", err
+// ReactivateUser lifts a user's suspension if it has expired, reporting
+// whether the user was reactivated
+func (db *DB) ReactivateUser(userID int64) (bool, error) {
+	query := `
+		UPDATE users
+		SET is_active = TRUE, suspended_until = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND is_active = FALSE AND suspended_until IS NOT NULL AND suspended_until <= ?
+	`
+
+	result, err := db.exec(query, userID, dbTime(time.Now()))
+	if err != nil {
+		return false, fmt.Errorf("failed to reactivate user: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
 	}
-	return hex.EncodeToString(bytes), nil
+
+	return rows > 0, nil
+}
+
+// ReactivateExpiredSuspensions lifts every suspension that has expired and
+// returns how many users were reactivated
+func (db *DB) ReactivateExpiredSuspensions() (int64, error) {
+	query := `
+		UPDATE users
+		SET is_active = TRUE, suspended_until = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE is_active = FALSE AND suspended_until IS NOT NULL AND suspended_until <= ?
+	`
+
+	result, err := db.exec(query, dbTime(time.Now()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to reactivate users: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows, nil
 }
@@ -0,0 +1,438 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/quentinsteinke/mkvmender/internal/models"
+)
+
+const (
+	// singleTargetMinVotes is how many upvotes an account needs, all on one
+	// submitter's work, before it is flagged
+	singleTargetMinVotes = 5
+	// ringMinVotes is how many of each other's submissions two accounts
+	// must both upvote before they are flagged as a voting ring
+	ringMinVotes = 3
+	// burstMinVotes is how many upvotes from new accounts a submission
+	// must get within burstWindow to be flagged
+	burstMinVotes = 3
+	burstWindow   = time.Hour
+	// newAccountAge is how old an account can be when voting and still
+	// count towards a burst
+	newAccountAge = 24 * time.Hour
+	// burstLookback limits how far back bursts are looked for
+	burstLookback = 7 * 24 * time.Hour
+)
+
+// voteFinding is suspicious voting found by DetectVoteManipulation
+type voteFinding struct {
+	voterID   int64
+	targetID  int64
+	pattern   string
+	details   string
+	voteCount int
+}
+
+// DetectVoteManipulation scans votes for accounts that only vote for one
+// submitter, bursts of upvotes from new accounts and pairs of accounts that
+// upvote each other, and adds what it finds to the review queue. Only
+// counted votes on live, published submissions are considered. Each voter
+// and target pair is flagged once per pattern; an open flag is updated as
+// its pattern grows. When neutralize is set, the flagged votes are left out
+// of scores straight away. It returns how many new flags were raised.
+func (db *DB) DetectVoteManipulation(neutralize bool) (int, error) {
+	var findings []voteFinding
+
+	singleTarget, err := db.findSingleTargetVoters()
+	if err != nil {
+		return 0, err
+	}
+	findings = append(findings, singleTarget...)
+
+	bursts, err := db.findNewAccountBursts(time.Now())
+	if err != nil {
+		return 0, err
+	}
+	findings = append(findings, bursts...)
+
+	rings, err := db.findVotingRings()
+	if err != nil {
+		return 0, err
+	}
+	findings = append(findings, rings...)
+
+	flagged := 0
+	err = db.inTx(func(tx *dbTx) error {
+		for _, f := range findings {
+			result, err := tx.exec(`
+				INSERT INTO vote_flags (voter_id, target_user_id, pattern, details, vote_count, neutralized)
+				VALUES (?, ?, ?, ?, ?, ?)
+				ON CONFLICT (voter_id, target_user_id, pattern) DO NOTHING
+			`, f.voterID, f.targetID, f.pattern, f.details, f.voteCount, neutralize)
+			if err != nil {
+				return fmt.Errorf("failed to flag votes: %w", err)
+			}
+			if n, err := result.RowsAffected(); err == nil && n > 0 {
+				flagged += int(n)
+				continue
+			}
+
+			// Already flagged: keep an open flag in step with a growing
+			// pattern so moderators review its full extent
+			_, err = tx.exec(`
+				UPDATE vote_flags
+				SET details = ?, vote_count = ?
+				WHERE voter_id = ? AND target_user_id = ? AND pattern = ?
+				  AND status = ? AND vote_count < ?
+			`, f.details, f.voteCount, f.voterID, f.targetID, f.pattern, models.FlagOpen, f.voteCount)
+			if err != nil {
+				return fmt.Errorf("failed to update vote flag: %w", err)
+			}
+		}
+
+		// Also catches votes cast since a flag was neutralized
+		return syncNeutralizedVotes(tx)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return flagged, nil
+}
+
+// flaggableVotes restricts votes v on submissions ns to those detection
+// looks at: votes that still count, on submissions that are neither deleted
+// nor awaiting approval
+const flaggableVotes = `v.neutralized = FALSE AND ns.deleted_at IS NULL AND ns.pending = FALSE`
+
+// findSingleTargetVoters finds accounts whose votes all went to one other
+// user's submissions
+func (db *DB) findSingleTargetVoters() ([]voteFinding, error) {
+	query := `
+		SELECT v.user_id, MIN(ns.user_id), SUM(CASE WHEN v.vote_type = 1 THEN 1 ELSE 0 END)
+		FROM votes v
+		JOIN naming_submissions ns ON ns.id = v.submission_id
+		WHERE v.user_id != ns.user_id AND ` + flaggableVotes + `
+		GROUP BY v.user_id
+		HAVING COUNT(DISTINCT ns.user_id) = 1
+		   AND SUM(CASE WHEN v.vote_type = 1 THEN 1 ELSE 0 END) >= ?
+	`
+
+	rows, err := db.query(query, singleTargetMinVotes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query votes: %w", err)
+	}
+	defer rows.Close()
+
+	var findings []voteFinding
+	for rows.Next() {
+		f := voteFinding{pattern: models.FlagSingleTarget}
+		if err := rows.Scan(&f.voterID, &f.targetID, &f.voteCount); err != nil {
+			return nil, fmt.Errorf("failed to scan votes: %w", err)
+		}
+		f.details = fmt.Sprintf("all %d upvotes went to one user's submissions", f.voteCount)
+		findings = append(findings, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return findings, nil
+}
+
+// findVotingRings finds pairs of accounts that each upvoted several of the
+// other's submissions. Both accounts in a pair are flagged.
+func (db *DB) findVotingRings() ([]voteFinding, error) {
+	query := `
+		WITH pairs AS (
+			SELECT v.user_id AS voter_id, ns.user_id AS target_id, COUNT(*) AS votes
+			FROM votes v
+			JOIN naming_submissions ns ON ns.id = v.submission_id
+			WHERE v.vote_type = 1 AND v.user_id != ns.user_id AND ` + flaggableVotes + `
+			GROUP BY v.user_id, ns.user_id
+		)
+		SELECT p.voter_id, p.target_id, p.votes, q.votes
+		FROM pairs p
+		JOIN pairs q ON q.voter_id = p.target_id AND q.target_id = p.voter_id
+		WHERE p.votes >= ? AND q.votes >= ?
+	`
+
+	rows, err := db.query(query, ringMinVotes, ringMinVotes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query votes: %w", err)
+	}
+	defer rows.Close()
+
+	var findings []voteFinding
+	for rows.Next() {
+		f := voteFinding{pattern: models.FlagVotingRing}
+		var returned int
+		if err := rows.Scan(&f.voterID, &f.targetID, &f.voteCount, &returned); err != nil {
+			return nil, fmt.Errorf("failed to scan votes: %w", err)
+		}
+		f.details = fmt.Sprintf("upvoted %d of the user's submissions and received %d upvotes back", f.voteCount, returned)
+		findings = append(findings, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return findings, nil
+}
+
+// findNewAccountBursts finds submissions that were upvoted by several new
+// accounts in quick succession, flagging each of those accounts
+func (db *DB) findNewAccountBursts(now time.Time) ([]voteFinding, error) {
+	query := `
+		SELECT v.submission_id, v.user_id, ns.user_id, v.created_at, u.created_at
+		FROM votes v
+		JOIN users u ON u.id = v.user_id
+		JOIN naming_submissions ns ON ns.id = v.submission_id
+		WHERE v.vote_type = 1 AND v.user_id != ns.user_id AND v.created_at >= ? AND ` + flaggableVotes + `
+		ORDER BY v.submission_id, v.created_at
+	`
+
+	rows, err := db.query(query, dbTime(now.Add(-burstLookback)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query votes: %w", err)
+	}
+	defer rows.Close()
+
+	type newVote struct {
+		voterID, targetID int64
+		at                time.Time
+	}
+	bySubmission := make(map[int64][]newVote)
+	for rows.Next() {
+		var submissionID int64
+		var v newVote
+		var accountCreated time.Time
+		if err := rows.Scan(&submissionID, &v.voterID, &v.targetID, &v.at, &accountCreated); err != nil {
+			return nil, fmt.Errorf("failed to scan votes: %w", err)
+		}
+		if v.at.Sub(accountCreated) < newAccountAge {
+			bySubmission[submissionID] = append(bySubmission[submissionID], v)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	type pair struct{ voterID, targetID int64 }
+	counts := make(map[pair]int)
+	var order []pair
+	for _, votes := range bySubmission {
+		burst := make(map[int]bool)
+		for start := range votes {
+			end := start
+			for end < len(votes) && votes[end].at.Sub(votes[start].at) <= burstWindow {
+				end++
+			}
+			if end-start >= burstMinVotes {
+				for i := start; i < end; i++ {
+					burst[i] = true
+				}
+			}
+		}
+
+		for i := range burst {
+			key := pair{votes[i].voterID, votes[i].targetID}
+			if counts[key] == 0 {
+				order = append(order, key)
+			}
+			counts[key]++
+		}
+	}
+
+	// Map iteration order is random; keep the flags stable
+	sort.Slice(order, func(i, j int) bool {
+		if order[i].voterID != order[j].voterID {
+			return order[i].voterID < order[j].voterID
+		}
+		return order[i].targetID < order[j].targetID
+	})
+
+	findings := make([]voteFinding, 0, len(order))
+	for _, key := range order {
+		findings = append(findings, voteFinding{
+			voterID:   key.voterID,
+			targetID:  key.targetID,
+			pattern:   models.FlagNewAccountBurst,
+			details:   fmt.Sprintf("upvoted within %gh of other accounts under %gh old", burstWindow.Hours(), newAccountAge.Hours()),
+			voteCount: counts[key],
+		})
+	}
+
+	return findings, nil
+}
+
+// syncNeutralizedVotes neutralizes the votes each neutralized flag covers,
+// a voter's votes on the target user's submissions, and restores any votes
+// no neutralized flag covers any more
+func syncNeutralizedVotes(q querier) error {
+	covered := `
+		EXISTS (
+			SELECT 1
+			FROM vote_flags f
+			JOIN naming_submissions ns ON ns.user_id = f.target_user_id
+			WHERE f.neutralized = TRUE
+			  AND f.voter_id = votes.user_id
+			  AND ns.id = votes.submission_id
+		)
+	`
+
+	if _, err := q.exec(`UPDATE votes SET neutralized = TRUE WHERE neutralized = FALSE AND ` + covered); err != nil {
+		return fmt.Errorf("failed to neutralize votes: %w", err)
+	}
+	if _, err := q.exec(`UPDATE votes SET neutralized = FALSE WHERE neutralized = TRUE AND NOT ` + covered); err != nil {
+		return fmt.Errorf("failed to restore votes: %w", err)
+	}
+
+	return nil
+}
+
+// voteFlagColumns is selected from vote_flags f joined with the voter (vu)
+// and the target (tu), in the order scanVoteFlag expects
+const voteFlagColumns = `f.id, f.voter_id, vu.username, f.target_user_id, tu.username, f.pattern,
+	f.details, f.vote_count, f.status, f.neutralized, f.reviewed_by, f.reviewed_at, f.created_at`
+
+// scanVoteFlag scans a row selected with voteFlagColumns
+func scanVoteFlag(row interface{ Scan(...interface{}) error }) (*models.VoteFlag, error) {
+	var f models.VoteFlag
+	var reviewedBy sql.NullInt64
+	var reviewedAt sql.NullTime
+	err := row.Scan(
+		&f.ID,
+		&f.VoterID,
+		&f.VoterUsername,
+		&f.TargetUserID,
+		&f.TargetUsername,
+		&f.Pattern,
+		&f.Details,
+		&f.VoteCount,
+		&f.Status,
+		&f.Neutralized,
+		&reviewedBy,
+		&reviewedAt,
+		&f.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if reviewedBy.Valid {
+		f.ReviewedBy = &reviewedBy.Int64
+	}
+	if reviewedAt.Valid {
+		f.ReviewedAt = &reviewedAt.Time
+	}
+	return &f, nil
+}
+
+// ListVoteFlags retrieves a paginated list of vote flags, newest first,
+// optionally only those with the given status
+func (db *DB) ListVoteFlags(status string, page, limit int) ([]models.VoteFlag, int, error) {
+	offset := (page - 1) * limit
+
+	whereClause := "WHERE 1=1"
+	args := []interface{}{}
+	if status != "" {
+		whereClause += " AND f.status = ?"
+		args = append(args, status)
+	}
+
+	var total int
+	err := db.queryRow(`SELECT COUNT(*) FROM vote_flags f `+whereClause, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count vote flags: %w", err)
+	}
+
+	query := `
+		SELECT ` + voteFlagColumns + `
+		FROM vote_flags f
+		JOIN users vu ON vu.id = f.voter_id
+		JOIN users tu ON tu.id = f.target_user_id
+		` + whereClause + `
+		ORDER BY f.created_at DESC, f.id DESC
+		LIMIT ? OFFSET ?
+	`
+
+	args = append(args, limit, offset)
+	rows, err := db.query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query vote flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []models.VoteFlag{}
+	for rows.Next() {
+		f, err := scanVoteFlag(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan vote flag: %w", err)
+		}
+		flags = append(flags, *f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows error: %w", err)
+	}
+
+	return flags, total, nil
+}
+
+// GetVoteFlag retrieves a vote flag by ID
+func (db *DB) GetVoteFlag(id int64) (*models.VoteFlag, error) {
+	query := `
+		SELECT ` + voteFlagColumns + `
+		FROM vote_flags f
+		JOIN users vu ON vu.id = f.voter_id
+		JOIN users tu ON tu.id = f.target_user_id
+		WHERE f.id = ?
+	`
+
+	f, err := scanVoteFlag(db.queryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("vote flag not found")
+		}
+		return nil, fmt.Errorf("failed to get vote flag: %w", err)
+	}
+
+	return f, nil
+}
+
+// ReviewVoteFlag records a moderator's decision on a vote flag. Confirming
+// it neutralizes the flagged votes; dismissing it restores them unless
+// another neutralized flag still covers them.
+func (db *DB) ReviewVoteFlag(id, reviewerID int64, confirm bool) error {
+	status := models.FlagDismissed
+	if confirm {
+		status = models.FlagConfirmed
+	}
+
+	return db.inTx(func(tx *dbTx) error {
+		result, err := tx.exec(`
+			UPDATE vote_flags
+			SET status = ?, neutralized = ?, reviewed_by = ?, reviewed_at = ?
+			WHERE id = ?
+		`, status, confirm, reviewerID, dbTime(time.Now()), id)
+		if err != nil {
+			return fmt.Errorf("failed to review vote flag: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows == 0 {
+			return fmt.Errorf("vote flag not found")
+		}
+
+		return syncNeutralizedVotes(tx)
+	})
+}
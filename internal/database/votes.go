@@ -2,27 +2,50 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/quentinsteinke/mkvmender/internal/models"
 )
 
-// CreateOrUpdateVote creates a new vote or updates existing one
-func (db *DB) CreateOrUpdateVote(submissionID, userID int64, voteType models.VoteType) error {
+// ErrSelfVote is returned when a user votes on their own submission
+var ErrSelfVote = errors.New("cannot vote on your own submission")
+
+// CreateOrUpdateVote creates a new vote or updates existing one. weight is
+// how much the vote counts, from the voter's trust level. Users cannot vote
+// on their own submissions, and submissions that lookups leave out (pending
+// approval or hidden by reports) are not found.
+func (db *DB) CreateOrUpdateVote(submissionID, userID int64, voteType models.VoteType, weight float64) error {
+	var ownerID int64
+	err := db.queryRow(`
+		SELECT user_id FROM naming_submissions
+		WHERE id = ? AND deleted_at IS NULL AND pending = FALSE AND hidden = FALSE
+	`, submissionID).Scan(&ownerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("submission not found")
+		}
+		return fmt.Errorf("failed to get submission: %w", err)
+	}
+	if ownerID == userID {
+		return ErrSelfVote
+	}
+
 	// Check if vote already exists
 	existing, err := db.GetVoteBySubmissionAndUser(submissionID, userID)
 	if err == nil && existing != nil {
 		// Update existing vote
-		return db.UpdateVote(existing.ID, voteType)
+		return db.UpdateVote(existing.ID, voteType, weight)
 	}
 
 	// Create new vote
 	query := `
-		INSERT INTO votes (submission_id, user_id, vote_type)
-		VALUES (?, ?, ?)
+		INSERT INTO votes (submission_id, user_id, vote_type, weight)
+		VALUES (?, ?, ?, ?)
 	`
 
-	_, err = db.conn.Exec(query, submissionID, userID, int(voteType))
+	_, err = db.exec(query, submissionID, userID, int(voteType), weight)
 	if err != nil {
 		return fmt.Errorf("failed to create vote: %w", err)
 	}
@@ -31,14 +54,14 @@ func (db *DB) CreateOrUpdateVote(submissionID, userID int64, voteType models.Vot
 }
 
 // UpdateVote updates an existing vote
-func (db *DB) UpdateVote(voteID int64, voteType models.VoteType) error {
+func (db *DB) UpdateVote(voteID int64, voteType models.VoteType, weight float64) error {
 	query := `
 		UPDATE votes
-		SET vote_type = ?, updated_at = CURRENT_TIMESTAMP
+		SET vote_type = ?, weight = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
 	`
 
-	_, err := db.conn.Exec(query, int(voteType), voteID)
+	_, err := db.exec(query, int(voteType), weight, voteID)
 	if err != nil {
 		return fmt.Errorf("failed to update vote: %w", err)
 	}
@@ -57,7 +80,7 @@ func (db *DB) GetVoteBySubmissionAndUser(submissionID, userID int64) (*models.Vo
 	var vote models.Vote
 	var voteTypeInt int
 
-	err := db.conn.QueryRow(query, submissionID, userID).Scan(
+	err := db.queryRow(query, submissionID, userID).Scan(
 		&vote.ID,
 		&vote.SubmissionID,
 		&vote.UserID,
@@ -77,6 +100,48 @@ func (db *DB) GetVoteBySubmissionAndUser(submissionID, userID int64) (*models.Vo
 	return &vote, nil
 }
 
+// GetUserVotes returns how userID voted on each of the given submissions,
+// keyed by submission ID. Submissions they have not voted on are absent.
+func (db *DB) GetUserVotes(userID int64, submissionIDs []int64) (map[int64]models.VoteType, error) {
+	votes := make(map[int64]models.VoteType, len(submissionIDs))
+	if len(submissionIDs) == 0 {
+		return votes, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(submissionIDs)), ", ")
+	args := []interface{}{userID}
+	for _, id := range submissionIDs {
+		args = append(args, id)
+	}
+
+	query := `
+		SELECT submission_id, vote_type
+		FROM votes
+		WHERE user_id = ? AND submission_id IN (` + placeholders + `)
+	`
+
+	rows, err := db.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query votes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var submissionID int64
+		var voteType int
+		if err := rows.Scan(&submissionID, &voteType); err != nil {
+			return nil, fmt.Errorf("failed to scan vote: %w", err)
+		}
+		votes[submissionID] = models.VoteType(voteType)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return votes, nil
+}
+
 // DeleteVote deletes a vote
 func (db *DB) DeleteVote(submissionID, userID int64) error {
 	query := `
@@ -84,7 +149,7 @@ func (db *DB) DeleteVote(submissionID, userID int64) error {
 		WHERE submission_id = ? AND user_id = ?
 	`
 
-	result, err := db.conn.Exec(query, submissionID, userID)
+	result, err := db.exec(query, submissionID, userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete vote: %w", err)
 	}
@@ -112,7 +177,7 @@ func (db *DB) GetVoteCountBySubmission(submissionID int64) (upvotes, downvotes,
 		WHERE submission_id = ?
 	`
 
-	err = db.conn.QueryRow(query, submissionID).Scan(&upvotes, &downvotes, &score)
+	err = db.queryRow(query, submissionID).Scan(&upvotes, &downvotes, &score)
 	if err != nil {
 		return 0, 0, 0, fmt.Errorf("failed to get vote count: %w", err)
 	}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/quentinsteinke/mkvmender/internal/logging"
+	"github.com/quentinsteinke/mkvmender/internal/models"
+)
+
+// ExportHandler returns everything the service holds about the caller as a
+// JSON download
+// GET /api/me/export
+func (h *Handler) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	export, err := h.db.ExportUserData(user.ID)
+	if err != nil {
+		respondInternalError(w, r, "failed to export account data", err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "mkvmender-"+user.Username+".json"))
+	respondJSON(w, http.StatusOK, export)
+}
+
+// DeleteAccountHandler deletes the caller's account. What happens to their
+// submissions depends on Options.AccountDeletion.
+// DELETE /api/me
+func (h *Handler) DeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	var req models.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Confirm != user.Username {
+		respondError(w, http.StatusBadRequest, "confirm must be your username")
+		return
+	}
+
+	// Staff accounts are demoted first so the last admin cannot lock
+	// everyone out by accident
+	if user.Role.IsStaff() {
+		respondError(w, http.StatusForbidden, "staff accounts must be demoted to user before they can be deleted")
+		return
+	}
+
+	submissions, err := h.db.DeleteAccount(user.ID, h.opts.AccountDeletion)
+	if err != nil {
+		respondInternalError(w, r, "failed to delete account", err)
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("account deleted",
+		"policy", h.opts.AccountDeletion,
+		"submissions", submissions)
+
+	respondJSON(w, http.StatusOK, models.DeleteAccountResponse{
+		Policy:      h.opts.AccountDeletion,
+		Submissions: submissions,
+	})
+}
@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/quentinsteinke/mkvmender/internal/database"
+	"github.com/quentinsteinke/mkvmender/internal/logging"
 	"github.com/quentinsteinke/mkvmender/internal/models"
+	"github.com/quentinsteinke/mkvmender/internal/naming"
 )
 
 // AdminHandler holds dependencies for admin HTTP handlers
@@ -53,10 +59,12 @@ func (h *AdminHandler) ListSubmissionsHandler(w http.ResponseWriter, r *http.Req
 		sortBy = "date"
 	}
 
+	deleted := r.URL.Query().Get("deleted") == "true"
+
 	// Fetch submissions from database
-	submissions, total, err := h.db.AdminListSubmissions(page, limit, userID, sortBy)
+	submissions, total, err := h.db.AdminListSubmissions(page, limit, userID, sortBy, deleted)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to fetch submissions")
+		respondInternalError(w, r, "failed to fetch submissions", err)
 		return
 	}
 
@@ -98,7 +106,11 @@ func (h *AdminHandler) GetSubmissionHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Get metadata
-	metadata, _ := h.db.GetMetadataBySubmissionID(submissionID)
+	metadata, err := h.db.GetMetadataBySubmissionID(submissionID)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("failed to get metadata",
+			"submission_id", submissionID, "error", err)
+	}
 	if metadata != nil {
 		submission.Metadata = metadata
 	}
@@ -141,21 +153,411 @@ func (h *AdminHandler) DeleteSubmissionHandler(w http.ResponseWriter, r *http.Re
 	}
 
 	// Delete submission
-	if err := h.db.DeleteSubmission(submissionID); err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to delete submission")
+	if err := h.db.DeleteSubmission(submissionID, admin.ID); err != nil {
+		if err.Error() == "submission not found" {
+			respondError(w, http.StatusNotFound, "submission not found")
+			return
+		}
+		respondInternalError(w, r, "failed to delete submission", err)
 		return
 	}
 
 	// Log moderation action
-	reasonStr := ""
-	if req.Reason != nil {
-		reasonStr = *req.Reason
-	}
-	h.db.LogModerationAction(admin.ID, "delete_submission", "submission", submissionID, reasonStr)
+	h.logModerationAction(r, &models.ModerationAction{
+		AdminID:    admin.ID,
+		ActionType: models.ActionDeleteSubmission,
+		TargetType: models.TargetSubmission,
+		TargetID:   submissionID,
+		Reason:     req.Reason,
+	})
 
 	respondSuccess(w, "submission deleted successfully")
 }
 
+// RestoreSubmissionHandler handles undoing a submission's deletion
+// POST /api/admin/submissions/restore?id=123
+func (h *AdminHandler) RestoreSubmissionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	admin, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	submissionIDStr := r.URL.Query().Get("id")
+	if submissionIDStr == "" {
+		respondError(w, http.StatusBadRequest, "submission ID is required")
+		return
+	}
+
+	submissionID, err := strconv.ParseInt(submissionIDStr, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid submission ID")
+		return
+	}
+
+	var req models.ReviewSubmissionRequest
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&req)
+	}
+
+	if err := h.db.RestoreSubmission(submissionID); err != nil {
+		switch {
+		case errors.Is(err, database.ErrNotDeleted), errors.Is(err, database.ErrMerged):
+			respondError(w, http.StatusConflict, err.Error())
+		case err.Error() == "submission not found":
+			respondError(w, http.StatusNotFound, "submission not found")
+		default:
+			respondInternalError(w, r, "failed to restore submission", err)
+		}
+		return
+	}
+
+	h.logModerationAction(r, &models.ModerationAction{
+		AdminID:    admin.ID,
+		ActionType: models.ActionRestoreSubmission,
+		TargetType: models.TargetSubmission,
+		TargetID:   submissionID,
+		Reason:     req.Reason,
+	})
+
+	respondSuccess(w, "submission restored")
+}
+
+// SubmissionHistoryHandler handles listing a submission's revisions
+// GET /api/admin/submissions/history?id=123
+func (h *AdminHandler) SubmissionHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	submissionIDStr := r.URL.Query().Get("id")
+	if submissionIDStr == "" {
+		respondError(w, http.StatusBadRequest, "submission ID is required")
+		return
+	}
+
+	submissionID, err := strconv.ParseInt(submissionIDStr, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid submission ID")
+		return
+	}
+
+	submission, err := h.db.GetSubmissionByID(submissionID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "submission not found")
+		return
+	}
+
+	revisions, err := h.db.GetSubmissionRevisions(submissionID)
+	if err != nil {
+		respondInternalError(w, r, "failed to get submission history", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"submission": submission,
+		"revisions":  revisions,
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// RollbackSubmissionHandler handles restoring a submission to an earlier
+// revision, e.g. to undo vandalism
+// POST /api/admin/submissions/rollback?id=123
+func (h *AdminHandler) RollbackSubmissionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	admin, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	submissionIDStr := r.URL.Query().Get("id")
+	if submissionIDStr == "" {
+		respondError(w, http.StatusBadRequest, "submission ID is required")
+		return
+	}
+
+	submissionID, err := strconv.ParseInt(submissionIDStr, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid submission ID")
+		return
+	}
+
+	var req models.RollbackSubmissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.RevisionID == 0 {
+		respondError(w, http.StatusBadRequest, "revision_id is required")
+		return
+	}
+
+	if _, err := h.db.GetSubmissionByID(submissionID); err != nil {
+		respondError(w, http.StatusNotFound, "submission not found")
+		return
+	}
+
+	if err := h.db.RollbackSubmission(submissionID, req.RevisionID, admin.ID, req.Reason); err != nil {
+		if err.Error() == "revision not found" {
+			respondError(w, http.StatusNotFound, "revision not found")
+			return
+		}
+		respondInternalError(w, r, "failed to roll back submission", err)
+		return
+	}
+
+	h.logModerationAction(r, &models.ModerationAction{
+		AdminID:    admin.ID,
+		ActionType: models.ActionRollbackSubmission,
+		TargetType: models.TargetSubmission,
+		TargetID:   submissionID,
+		Reason:     req.Reason,
+	})
+
+	respondSuccess(w, fmt.Sprintf("submission rolled back to revision %d", req.RevisionID))
+}
+
+// DuplicatesHandler handles listing groups of duplicate submissions
+// GET /api/admin/submissions/duplicates
+func (h *AdminHandler) DuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	duplicates, err := h.db.GetDuplicateSubmissions()
+	if err != nil {
+		respondInternalError(w, r, "failed to find duplicates", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, duplicates)
+}
+
+// MergeSubmissionsHandler handles merging duplicate submissions, and their
+// votes, into one
+// POST /api/admin/submissions/merge?id=123
+func (h *AdminHandler) MergeSubmissionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	admin, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	targetIDStr := r.URL.Query().Get("id")
+	if targetIDStr == "" {
+		respondError(w, http.StatusBadRequest, "submission ID is required")
+		return
+	}
+
+	targetID, err := strconv.ParseInt(targetIDStr, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid submission ID")
+		return
+	}
+
+	var req models.MergeSubmissionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(req.SourceIDs) == 0 {
+		respondError(w, http.StatusBadRequest, "source_ids is required")
+		return
+	}
+	for _, id := range req.SourceIDs {
+		if id == targetID {
+			respondError(w, http.StatusBadRequest, "cannot merge a submission into itself")
+			return
+		}
+	}
+
+	if err := h.db.MergeSubmissions(targetID, req.SourceIDs, admin.ID); err != nil {
+		switch {
+		case errors.Is(err, database.ErrDifferentFiles):
+			respondError(w, http.StatusBadRequest, err.Error())
+		case err.Error() == "submission not found":
+			respondError(w, http.StatusNotFound, "submission not found")
+		default:
+			respondInternalError(w, r, "failed to merge submissions", err)
+		}
+		return
+	}
+
+	for _, id := range req.SourceIDs {
+		reason := fmt.Sprintf("merged into submission %d", targetID)
+		if req.Reason != nil {
+			reason += ": " + *req.Reason
+		}
+		h.logModerationAction(r, &models.ModerationAction{
+			AdminID:    admin.ID,
+			ActionType: models.ActionMergeSubmission,
+			TargetType: models.TargetSubmission,
+			TargetID:   id,
+			Reason:     &reason,
+		})
+	}
+
+	submission, err := h.db.GetSubmissionByID(targetID)
+	if err != nil {
+		respondInternalError(w, r, "failed to get submission", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, submission)
+}
+
+// PendingSubmissionsHandler handles listing submissions awaiting approval,
+// oldest first
+// GET /api/admin/submissions/pending
+func (h *AdminHandler) PendingSubmissionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	submissions, total, err := h.db.GetPendingSubmissions(page, limit)
+	if err != nil {
+		respondInternalError(w, r, "failed to fetch pending submissions", err)
+		return
+	}
+
+	for i := range submissions {
+		meta, err := h.db.GetMetadataBySubmissionID(submissions[i].ID)
+		if err != nil {
+			respondInternalError(w, r, "failed to get metadata", err)
+			return
+		}
+		submissions[i].Metadata = meta
+	}
+
+	response := map[string]interface{}{
+		"submissions": submissions,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// ApproveSubmissionHandler handles publishing a submission awaiting approval
+// POST /api/admin/submissions/approve?id=123
+func (h *AdminHandler) ApproveSubmissionHandler(w http.ResponseWriter, r *http.Request) {
+	h.reviewPendingSubmission(w, r, true)
+}
+
+// RejectSubmissionHandler handles deleting a submission awaiting approval
+// POST /api/admin/submissions/reject?id=123
+func (h *AdminHandler) RejectSubmissionHandler(w http.ResponseWriter, r *http.Request) {
+	h.reviewPendingSubmission(w, r, false)
+}
+
+// reviewPendingSubmission approves or rejects the pending submission named
+// by the id query parameter and logs the decision
+func (h *AdminHandler) reviewPendingSubmission(w http.ResponseWriter, r *http.Request, approve bool) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	admin, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	submissionIDStr := r.URL.Query().Get("id")
+	if submissionIDStr == "" {
+		respondError(w, http.StatusBadRequest, "submission ID is required")
+		return
+	}
+
+	submissionID, err := strconv.ParseInt(submissionIDStr, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid submission ID")
+		return
+	}
+
+	var req models.ReviewSubmissionRequest
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&req)
+	}
+
+	action := &models.ModerationAction{
+		AdminID:    admin.ID,
+		TargetType: models.TargetSubmission,
+		TargetID:   submissionID,
+		Reason:     req.Reason,
+	}
+
+	if approve {
+		if err := h.db.ApproveSubmission(submissionID); err != nil {
+			switch {
+			case errors.Is(err, database.ErrNotPending):
+				respondError(w, http.StatusConflict, err.Error())
+			case err.Error() == "submission not found":
+				respondError(w, http.StatusNotFound, "submission not found")
+			default:
+				respondInternalError(w, r, "failed to approve submission", err)
+			}
+			return
+		}
+		action.ActionType = models.ActionApproveSubmission
+	} else {
+		submission, err := h.db.GetSubmissionByID(submissionID)
+		if err != nil {
+			respondError(w, http.StatusNotFound, "submission not found")
+			return
+		}
+		if !submission.Pending {
+			respondError(w, http.StatusConflict, database.ErrNotPending.Error())
+			return
+		}
+		if err := h.db.DeleteSubmission(submissionID, admin.ID); err != nil {
+			respondInternalError(w, r, "failed to reject submission", err)
+			return
+		}
+		action.ActionType = models.ActionRejectSubmission
+	}
+
+	h.logModerationAction(r, action)
+
+	if approve {
+		respondSuccess(w, "submission approved")
+	} else {
+		respondSuccess(w, "submission rejected")
+	}
+}
+
 // ListUsersHandler handles listing all users with pagination and filters
 // GET /api/admin/users?page=1&limit=50&role=admin&status=active
 func (h *AdminHandler) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
@@ -182,7 +584,7 @@ func (h *AdminHandler) ListUsersHandler(w http.ResponseWriter, r *http.Request)
 	// Fetch users from database
 	users, total, err := h.db.AdminListUsers(page, limit, role, status)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to fetch users")
+		respondInternalError(w, r, "failed to fetch users", err)
 		return
 	}
 
@@ -275,12 +677,19 @@ func (h *AdminHandler) ChangeUserRoleHandler(w http.ResponseWriter, r *http.Requ
 
 	// Update user role
 	if err := h.db.UpdateUserRole(userID, req.Role); err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to update user role")
+		respondInternalError(w, r, "failed to update user role", err)
 		return
 	}
 
 	// Log moderation action
-	h.db.LogModerationAction(admin.ID, "change_role", "user", userID, string(req.Role))
+	role := string(req.Role)
+	h.logModerationAction(r, &models.ModerationAction{
+		AdminID:    admin.ID,
+		ActionType: models.ActionChangeRole,
+		TargetType: models.TargetUser,
+		TargetID:   userID,
+		Reason:     &role,
+	})
 
 	respondSuccess(w, "user role updated successfully")
 }
@@ -326,43 +735,869 @@ func (h *AdminHandler) ChangeUserStatusHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// Moderators may not suspend other staff
+	target, err := h.db.GetUserByID(userID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	if !admin.Role.CanModerate(target.Role) {
+		respondError(w, http.StatusForbidden, fmt.Sprintf("role %s cannot change the status of a user with role %s", admin.Role, target.Role))
+		return
+	}
+
+	// Resolve the end of a temporary suspension
+	suspendedUntil, err := suspensionEnd(req, time.Now())
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Update user status
-	if err := h.db.UpdateUserStatus(userID, req.IsActive); err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to update user status")
+	if err := h.db.UpdateUserStatus(userID, req.IsActive, suspendedUntil); err != nil {
+		respondInternalError(w, r, "failed to update user status", err)
 		return
 	}
 
 	// Log moderation action
-	actionType := "suspend_user"
+	actionType := models.ActionSuspendUser
 	if req.IsActive {
-		actionType = "activate_user"
-	}
-	reasonStr := ""
-	if req.Reason != nil {
-		reasonStr = *req.Reason
+		actionType = models.ActionActivateUser
 	}
-	h.db.LogModerationAction(admin.ID, actionType, "user", userID, reasonStr)
+	h.logModerationAction(r, &models.ModerationAction{
+		AdminID:    admin.ID,
+		ActionType: actionType,
+		TargetType: models.TargetUser,
+		TargetID:   userID,
+		Reason:     req.Reason,
+		ExpiresAt:  suspendedUntil,
+	})
 
-	message := "user suspended successfully"
-	if req.IsActive {
-		message = "user activated successfully"
+	message := "user activated successfully"
+	if !req.IsActive {
+		message = "user suspended successfully"
+		if suspendedUntil != nil {
+			message = fmt.Sprintf("user suspended until %s", suspendedUntil.UTC().Format(time.RFC3339))
+		}
 	}
 	respondSuccess(w, message)
 }
 
-// GetStatsHandler handles fetching admin dashboard statistics
-// GET /api/admin/stats
-func (h *AdminHandler) GetStatsHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// maxBulkHashes caps how many file hashes one bulk deletion may name
+const maxBulkHashes = 1000
+
+// BulkDeleteUserSubmissionsHandler handles deleting every submission by one
+// user
+// POST /api/admin/bulk/delete-user-submissions?id=123
+func (h *AdminHandler) BulkDeleteUserSubmissionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	admin, target, ok := h.bulkTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.BulkDeleteRequest
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&req)
+	}
+	if req.Pattern != "" || len(req.Hashes) > 0 {
+		respondError(w, http.StatusBadRequest, "pattern and hashes are not accepted here; use /api/admin/bulk/delete-submissions")
+		return
+	}
+
+	filter := database.BulkSubmissionFilter{UserID: &target.ID}
+	result, err := h.db.BulkDeleteSubmissions(filter, admin.ID, req.DryRun, &models.ModerationAction{
+		AdminID:    admin.ID,
+		ActionType: models.ActionBulkDelete,
+		TargetType: models.TargetUser,
+		TargetID:   target.ID,
+		Reason:     req.Reason,
+	})
+	if err != nil {
+		respondInternalError(w, r, "failed to delete submissions", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+// BulkDeleteSubmissionsHandler handles deleting every submission whose
+// filename matches a pattern or whose file is in a list of hashes
+// POST /api/admin/bulk/delete-submissions
+func (h *AdminHandler) BulkDeleteSubmissionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	admin, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	var req models.BulkDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	req.Pattern = strings.TrimSpace(req.Pattern)
+	if req.Pattern == "" && len(req.Hashes) == 0 {
+		respondError(w, http.StatusBadRequest, "pattern or hashes is required")
+		return
+	}
+	if req.Pattern != "" && strings.Trim(req.Pattern, "*?") == "" {
+		respondError(w, http.StatusBadRequest, "pattern must contain more than wildcards")
+		return
+	}
+	if len(req.Hashes) > maxBulkHashes {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("at most %d hashes may be given", maxBulkHashes))
+		return
+	}
+	for i, hash := range req.Hashes {
+		req.Hashes[i] = strings.ToLower(strings.TrimSpace(hash))
+		if req.Hashes[i] == "" {
+			respondError(w, http.StatusBadRequest, "hashes must not be empty")
+			return
+		}
+	}
+
+	filter := database.BulkSubmissionFilter{Pattern: req.Pattern, Hashes: req.Hashes}
+	result, err := h.db.BulkDeleteSubmissions(filter, admin.ID, req.DryRun, &models.ModerationAction{
+		AdminID:    admin.ID,
+		ActionType: models.ActionBulkDelete,
+		TargetType: models.TargetSubmissions,
+		Reason:     req.Reason,
+	})
+	if err != nil {
+		respondInternalError(w, r, "failed to delete submissions", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+// BulkSuspendHandler handles suspending a user and deleting every vote they
+// cast
+// POST /api/admin/bulk/suspend-and-purge-votes?id=123
+func (h *AdminHandler) BulkSuspendHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	admin, target, ok := h.bulkTargetUser(w, r)
+	if !ok {
+		return
+	}
+	if target.ID == admin.ID {
+		respondError(w, http.StatusForbidden, "cannot change your own status")
+		return
+	}
+
+	var req models.BulkSuspendRequest
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&req)
+	}
+
+	suspendedUntil, err := suspensionEnd(models.ChangeStatusRequest{
+		SuspendedUntil: req.SuspendedUntil,
+		Duration:       req.Duration,
+	}, time.Now())
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.db.SuspendUserAndPurgeVotes(target.ID, suspendedUntil, req.DryRun, &models.ModerationAction{
+		AdminID:    admin.ID,
+		ActionType: models.ActionSuspendPurgeVotes,
+		TargetType: models.TargetUser,
+		TargetID:   target.ID,
+		Reason:     req.Reason,
+	})
+	if err != nil {
+		respondInternalError(w, r, "failed to suspend user", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+// bulkTargetUser loads the user named by the id query parameter and checks
+// that the caller may moderate them, writing an error response and
+// returning false otherwise
+func (h *AdminHandler) bulkTargetUser(w http.ResponseWriter, r *http.Request) (*models.User, *models.User, bool) {
+	admin, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return nil, nil, false
+	}
+
+	userIDStr := r.URL.Query().Get("id")
+	if userIDStr == "" {
+		respondError(w, http.StatusBadRequest, "user ID is required")
+		return nil, nil, false
+	}
+
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid user ID")
+		return nil, nil, false
+	}
+
+	target, err := h.db.GetUserByID(userID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "user not found")
+		return nil, nil, false
+	}
+	if target.ID != admin.ID && !admin.Role.CanModerate(target.Role) {
+		respondError(w, http.StatusForbidden, fmt.Sprintf("role %s cannot moderate a user with role %s", admin.Role, target.Role))
+		return nil, nil, false
+	}
+
+	return admin, target, true
+}
+
+// ListActionsHandler handles listing the moderation log, newest first
+// GET /api/admin/actions?admin_id=1&action_type=suspend_user&target_type=user&target_id=5&since=2024-01-01T00:00:00Z&until=...
+func (h *AdminHandler) ListActionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	filter := database.ActionFilter{
+		ActionType: query.Get("action_type"),
+		TargetType: query.Get("target_type"),
+	}
+	for name, dest := range map[string]**int64{
+		"admin_id":  &filter.AdminID,
+		"target_id": &filter.TargetID,
+	} {
+		if value := query.Get(name); value != "" {
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s", name))
+				return
+			}
+			*dest = &id
+		}
+	}
+	for name, dest := range map[string]**time.Time{
+		"since": &filter.Since,
+		"until": &filter.Until,
+	} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, fmt.Sprintf("%s must be an RFC 3339 time", name))
+				return
+			}
+			*dest = &t
+		}
+	}
+
+	actions, total, err := h.db.ListModerationActions(filter, page, limit)
+	if err != nil {
+		respondInternalError(w, r, "failed to fetch moderation actions", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"actions": actions,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// TargetTimelineHandler handles listing every moderation action taken on one
+// target, oldest first
+// GET /api/admin/actions/timeline?target_type=submission&target_id=123
+func (h *AdminHandler) TargetTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	targetType := r.URL.Query().Get("target_type")
+	switch targetType {
+	case models.TargetUser, models.TargetSubmission, models.TargetInvite, models.TargetVoteFlag:
+	default:
+		respondError(w, http.StatusBadRequest, "target_type must be user, submission, invite or vote_flag")
+		return
+	}
+
+	targetIDStr := r.URL.Query().Get("target_id")
+	if targetIDStr == "" {
+		respondError(w, http.StatusBadRequest, "target ID is required")
+		return
+	}
+
+	targetID, err := strconv.ParseInt(targetIDStr, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid target ID")
+		return
+	}
+
+	actions, err := h.db.GetTargetTimeline(targetType, targetID)
+	if err != nil {
+		respondInternalError(w, r, "failed to fetch moderation actions", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"target_type": targetType,
+		"target_id":   targetID,
+		"actions":     actions,
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// GetStatsHandler handles fetching admin dashboard statistics
+// GET /api/admin/stats
+func (h *AdminHandler) GetStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
 		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	stats, err := h.db.GetAdminStats()
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to fetch statistics")
+		respondInternalError(w, r, "failed to fetch statistics", err)
 		return
 	}
 
 	respondJSON(w, http.StatusOK, stats)
 }
+
+// ListVoteFlagsHandler handles listing the vote manipulation review queue
+// GET /api/admin/vote-flags?status=open
+func (h *AdminHandler) ListVoteFlagsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.FlagOpen, models.FlagConfirmed, models.FlagDismissed:
+	default:
+		respondError(w, http.StatusBadRequest, "status must be open, confirmed or dismissed")
+		return
+	}
+
+	flags, total, err := h.db.ListVoteFlags(status, page, limit)
+	if err != nil {
+		respondInternalError(w, r, "failed to fetch vote flags", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"flags": flags,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// ReviewVoteFlagHandler handles confirming a vote flag, which neutralizes
+// the flagged votes, or dismissing it, which restores them
+// POST /api/admin/vote-flags/review?id=123
+func (h *AdminHandler) ReviewVoteFlagHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	admin, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	flagIDStr := r.URL.Query().Get("id")
+	if flagIDStr == "" {
+		respondError(w, http.StatusBadRequest, "vote flag ID is required")
+		return
+	}
+
+	flagID, err := strconv.ParseInt(flagIDStr, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid vote flag ID")
+		return
+	}
+
+	var req models.ReviewVoteFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	var action string
+	switch req.Action {
+	case "confirm":
+		action = models.ActionConfirmVoteFlag
+	case "dismiss":
+		action = models.ActionDismissVoteFlag
+	default:
+		respondError(w, http.StatusBadRequest, "action must be confirm or dismiss")
+		return
+	}
+
+	if err := h.db.ReviewVoteFlag(flagID, admin.ID, req.Action == "confirm"); err != nil {
+		if err.Error() == "vote flag not found" {
+			respondError(w, http.StatusNotFound, "vote flag not found")
+			return
+		}
+		respondInternalError(w, r, "failed to review vote flag", err)
+		return
+	}
+
+	h.logModerationAction(r, &models.ModerationAction{
+		AdminID:    admin.ID,
+		ActionType: action,
+		TargetType: models.TargetVoteFlag,
+		TargetID:   flagID,
+		Reason:     req.Reason,
+	})
+
+	flag, err := h.db.GetVoteFlag(flagID)
+	if err != nil {
+		respondInternalError(w, r, "failed to get vote flag", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, flag)
+}
+
+// ListReportsHandler handles listing the submission report queue, oldest
+// first
+// GET /api/admin/reports?status=open
+func (h *AdminHandler) ListReportsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.ReportOpen, models.ReportResolved, models.ReportDismissed:
+	default:
+		respondError(w, http.StatusBadRequest, "status must be open, resolved or dismissed")
+		return
+	}
+
+	reports, total, err := h.db.ListReports(status, page, limit)
+	if err != nil {
+		respondInternalError(w, r, "failed to fetch reports", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"reports": reports,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// ResolveReportHandler handles resolving the open reports on a reported
+// submission by dismissing them, deleting the submission or editing it
+// POST /api/admin/reports/resolve?id=123
+func (h *AdminHandler) ResolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	admin, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	reportIDStr := r.URL.Query().Get("id")
+	if reportIDStr == "" {
+		respondError(w, http.StatusBadRequest, "report ID is required")
+		return
+	}
+
+	reportID, err := strconv.ParseInt(reportIDStr, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid report ID")
+		return
+	}
+
+	var req models.ResolveReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	report, err := h.db.GetReport(reportID)
+	if err != nil {
+		if err.Error() == "report not found" {
+			respondError(w, http.StatusNotFound, "report not found")
+			return
+		}
+		respondInternalError(w, r, "failed to get report", err)
+		return
+	}
+	if report.Status != models.ReportOpen {
+		respondError(w, http.StatusConflict, "report has already been resolved")
+		return
+	}
+
+	action := &models.ModerationAction{
+		AdminID:    admin.ID,
+		TargetType: models.TargetSubmission,
+		TargetID:   report.SubmissionID,
+		Reason:     req.Reason,
+	}
+	status := models.ReportResolved
+
+	switch req.Action {
+	case "dismiss":
+		action.ActionType = models.ActionDismissReports
+		status = models.ReportDismissed
+
+	case "delete":
+		if !admin.Role.Can(models.PermDeleteSubmissions) {
+			respondError(w, http.StatusForbidden, fmt.Sprintf("the %s role lacks the %q permission", admin.Role, models.PermDeleteSubmissions))
+			return
+		}
+		action.ActionType = models.ActionDeleteSubmission
+
+	case "edit":
+		filename := strings.TrimSpace(req.Filename)
+		if filename == "" && req.Metadata == nil {
+			respondError(w, http.StatusBadRequest, "filename or metadata is required")
+			return
+		}
+		if filename == "" {
+			filename = report.Filename
+		}
+		if err := naming.CheckFilename(filename); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := h.db.UpdateSubmission(report.SubmissionID, admin.ID, filename, req.Metadata, req.Reason, false); err != nil {
+			respondInternalError(w, r, "failed to edit submission", err)
+			return
+		}
+		action.ActionType = models.ActionEditSubmission
+
+	default:
+		respondError(w, http.StatusBadRequest, "action must be dismiss, delete or edit")
+		return
+	}
+
+	var closed int
+	if req.Action == "delete" {
+		closed, err = h.db.ResolveReportsAndDelete(report.SubmissionID, admin.ID)
+	} else {
+		closed, err = h.db.ResolveReports(report.SubmissionID, admin.ID, status)
+	}
+	if err != nil {
+		if err.Error() == "submission not found" {
+			respondError(w, http.StatusNotFound, "submission not found")
+			return
+		}
+		respondInternalError(w, r, "failed to resolve reports", err)
+		return
+	}
+
+	h.logModerationAction(r, action)
+
+	respondSuccess(w, fmt.Sprintf("%d report(s) on submission %d %s", closed, report.SubmissionID, status))
+}
+
+// maxInviteUses caps how many registrations one invite code allows
+const maxInviteUses = 1000
+
+// InvitesHandler handles listing (GET) and creating (POST) invite codes
+// GET/POST /api/admin/invites
+func (h *AdminHandler) InvitesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		invites, err := h.db.ListInvites()
+		if err != nil {
+			respondInternalError(w, r, "failed to list invites", err)
+			return
+		}
+		respondJSON(w, http.StatusOK, invites)
+	case http.MethodPost:
+		h.createInvite(w, r)
+	default:
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// createInvite creates an invite code
+func (h *AdminHandler) createInvite(w http.ResponseWriter, r *http.Request) {
+	admin, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	var req models.CreateInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.MaxUses == 0 {
+		req.MaxUses = 1
+	}
+	if req.MaxUses < 0 || req.MaxUses > maxInviteUses {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("max_uses must be between 1 and %d", maxInviteUses))
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.Duration != "" {
+		d, err := parseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("invalid duration %q (use e.g. \"72h\" or \"7d\")", req.Duration))
+			return
+		}
+		expires := time.Now().Add(d)
+		expiresAt = &expires
+	}
+
+	invite, err := h.db.CreateInvite(admin.ID, req.MaxUses, expiresAt)
+	if err != nil {
+		respondInternalError(w, r, "failed to create invite", err)
+		return
+	}
+
+	h.logModerationAction(r, &models.ModerationAction{
+		AdminID:    admin.ID,
+		ActionType: models.ActionCreateInvite,
+		TargetType: models.TargetInvite,
+		TargetID:   invite.ID,
+		ExpiresAt:  invite.ExpiresAt,
+	})
+
+	respondJSON(w, http.StatusCreated, invite)
+}
+
+// DeleteInviteHandler handles revoking an invite code
+// DELETE /api/admin/invites/delete?id=123
+func (h *AdminHandler) DeleteInviteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	admin, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	inviteID, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid invite ID")
+		return
+	}
+
+	if err := h.db.DeleteInvite(inviteID); err != nil {
+		if err.Error() == "invite not found" {
+			respondError(w, http.StatusNotFound, "invite not found")
+			return
+		}
+		respondInternalError(w, r, "failed to delete invite", err)
+		return
+	}
+
+	h.logModerationAction(r, &models.ModerationAction{
+		AdminID:    admin.ID,
+		ActionType: models.ActionRevokeInvite,
+		TargetType: models.TargetInvite,
+		TargetID:   inviteID,
+	})
+
+	respondSuccess(w, "invite revoked")
+}
+
+// UserKeysHandler lists a user's API keys, without their secrets
+// GET /api/admin/keys?id=<user id>
+func (h *AdminHandler) UserKeysHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	userID, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	if _, err := h.db.GetUserByID(userID); err != nil {
+		respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	keys, err := h.db.ListAPIKeys(userID)
+	if err != nil {
+		respondInternalError(w, r, "failed to list API keys", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.APIKeyListResponse{Keys: keys})
+}
+
+// RevokeKeyHandler revokes any user's API key, such as a leaked one
+// DELETE /api/admin/keys/revoke?id=<key id>
+func (h *AdminHandler) RevokeKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	admin, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	keyID, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid key ID")
+		return
+	}
+
+	key, err := h.db.RevokeAPIKey(keyID)
+	if err != nil {
+		if err.Error() == "api key not found" {
+			respondError(w, http.StatusNotFound, "api key not found")
+			return
+		}
+		respondInternalError(w, r, "failed to revoke API key", err)
+		return
+	}
+
+	details, err := json.Marshal(struct {
+		KeyID  int64  `json:"key_id"`
+		Name   string `json:"name"`
+		Prefix string `json:"prefix"`
+	}{key.ID, key.Name, key.Prefix})
+	if err != nil {
+		respondInternalError(w, r, "failed to encode action details", err)
+		return
+	}
+
+	h.logModerationAction(r, &models.ModerationAction{
+		AdminID:    admin.ID,
+		ActionType: models.ActionRevokeAPIKey,
+		TargetType: models.TargetUser,
+		TargetID:   key.UserID,
+		Details:    details,
+	})
+
+	respondSuccess(w, "api key revoked")
+}
+
+// suspensionEnd returns when a requested suspension should end, or nil for
+// activations and permanent suspensions
+func suspensionEnd(req models.ChangeStatusRequest, now time.Time) (*time.Time, error) {
+	if req.IsActive {
+		if req.SuspendedUntil != nil || req.Duration != "" {
+			return nil, fmt.Errorf("suspended_until and duration only apply to suspensions")
+		}
+		return nil, nil
+	}
+
+	switch {
+	case req.SuspendedUntil != nil && req.Duration != "":
+		return nil, fmt.Errorf("specify either suspended_until or duration, not both")
+	case req.SuspendedUntil != nil:
+		if !req.SuspendedUntil.After(now) {
+			return nil, fmt.Errorf("suspended_until must be in the future")
+		}
+		return req.SuspendedUntil, nil
+	case req.Duration != "":
+		d, err := parseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid duration %q (use e.g. \"72h\" or \"7d\")", req.Duration)
+		}
+		until := now.Add(d)
+		return &until, nil
+	}
+
+	return nil, nil
+}
+
+// parseDuration parses a Go duration, additionally accepting whole days
+// such as "7d"
+func parseDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// logModerationAction records an admin action, logging any failure since the
+// action itself has already been applied
+func (h *AdminHandler) logModerationAction(r *http.Request, action *models.ModerationAction) {
+	if err := h.db.LogModerationAction(action); err != nil {
+		logging.FromContext(r.Context()).Error("failed to log moderation action",
+			"action_type", action.ActionType,
+			"target_type", action.TargetType,
+			"target_id", action.TargetID,
+			"error", err)
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/quentinsteinke/mkvmender/internal/models"
+)
+
+// AdminRoute is an admin API route and the permission it requires
+type AdminRoute struct {
+	Pattern    string
+	Permission models.Permission
+	Handler    http.HandlerFunc
+}
+
+// Routes returns the admin API routes, each guarded by its permission in
+// the matrix (models.RolePermissions)
+func (h *AdminHandler) Routes() []AdminRoute {
+	return []AdminRoute{
+		{"/api/admin/submissions", models.PermViewSubmissions, h.ListSubmissionsHandler},
+		{"/api/admin/submissions/get", models.PermViewSubmissions, h.GetSubmissionHandler},
+		{"/api/admin/submissions/delete", models.PermDeleteSubmissions, h.DeleteSubmissionHandler},
+		{"/api/admin/submissions/history", models.PermViewSubmissions, h.SubmissionHistoryHandler},
+		{"/api/admin/submissions/rollback", models.PermEditSubmissions, h.RollbackSubmissionHandler},
+		{"/api/admin/submissions/duplicates", models.PermViewSubmissions, h.DuplicatesHandler},
+		{"/api/admin/submissions/merge", models.PermEditSubmissions, h.MergeSubmissionsHandler},
+		{"/api/admin/submissions/pending", models.PermViewSubmissions, h.PendingSubmissionsHandler},
+		{"/api/admin/submissions/approve", models.PermEditSubmissions, h.ApproveSubmissionHandler},
+		{"/api/admin/submissions/reject", models.PermDeleteSubmissions, h.RejectSubmissionHandler},
+		{"/api/admin/submissions/restore", models.PermDeleteSubmissions, h.RestoreSubmissionHandler},
+		{"/api/admin/users", models.PermViewUsers, h.ListUsersHandler},
+		{"/api/admin/users/get", models.PermViewUsers, h.GetUserHandler},
+		{"/api/admin/users/role", models.PermChangeRoles, h.ChangeUserRoleHandler},
+		{"/api/admin/users/status", models.PermSuspendUsers, h.ChangeUserStatusHandler},
+		{"/api/admin/reports", models.PermViewSubmissions, h.ListReportsHandler},
+		{"/api/admin/reports/resolve", models.PermEditSubmissions, h.ResolveReportHandler},
+		{"/api/admin/vote-flags", models.PermReviewVotes, h.ListVoteFlagsHandler},
+		{"/api/admin/vote-flags/review", models.PermReviewVotes, h.ReviewVoteFlagHandler},
+		{"/api/admin/bulk/delete-user-submissions", models.PermDeleteSubmissions, h.BulkDeleteUserSubmissionsHandler},
+		{"/api/admin/bulk/delete-submissions", models.PermDeleteSubmissions, h.BulkDeleteSubmissionsHandler},
+		{"/api/admin/bulk/suspend-and-purge-votes", models.PermSuspendUsers, h.BulkSuspendHandler},
+		{"/api/admin/actions", models.PermViewAuditLog, h.ListActionsHandler},
+		{"/api/admin/actions/timeline", models.PermViewAuditLog, h.TargetTimelineHandler},
+		{"/api/admin/stats", models.PermViewStats, h.GetStatsHandler},
+		{"/api/admin/invites", models.PermManageInvites, h.InvitesHandler},
+		{"/api/admin/invites/delete", models.PermManageInvites, h.DeleteInviteHandler},
+		{"/api/admin/keys", models.PermManageKeys, h.UserKeysHandler},
+		{"/api/admin/keys/revoke", models.PermManageKeys, h.RevokeKeyHandler},
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quentinsteinke/mkvmender/internal/models"
+)
+
+// adminRoutePermissions is the permission each admin route must require
+var adminRoutePermissions = map[string]models.Permission{
+	"/api/admin/submissions":                  models.PermViewSubmissions,
+	"/api/admin/submissions/get":              models.PermViewSubmissions,
+	"/api/admin/submissions/delete":           models.PermDeleteSubmissions,
+	"/api/admin/submissions/history":          models.PermViewSubmissions,
+	"/api/admin/submissions/rollback":         models.PermEditSubmissions,
+	"/api/admin/submissions/duplicates":       models.PermViewSubmissions,
+	"/api/admin/submissions/merge":            models.PermEditSubmissions,
+	"/api/admin/submissions/pending":          models.PermViewSubmissions,
+	"/api/admin/submissions/approve":          models.PermEditSubmissions,
+	"/api/admin/submissions/reject":           models.PermDeleteSubmissions,
+	"/api/admin/submissions/restore":          models.PermDeleteSubmissions,
+	"/api/admin/users":                        models.PermViewUsers,
+	"/api/admin/users/get":                    models.PermViewUsers,
+	"/api/admin/users/role":                   models.PermChangeRoles,
+	"/api/admin/users/status":                 models.PermSuspendUsers,
+	"/api/admin/reports":                      models.PermViewSubmissions,
+	"/api/admin/reports/resolve":              models.PermEditSubmissions,
+	"/api/admin/vote-flags":                   models.PermReviewVotes,
+	"/api/admin/vote-flags/review":            models.PermReviewVotes,
+	"/api/admin/bulk/delete-user-submissions": models.PermDeleteSubmissions,
+	"/api/admin/bulk/delete-submissions":      models.PermDeleteSubmissions,
+	"/api/admin/bulk/suspend-and-purge-votes": models.PermSuspendUsers,
+	"/api/admin/actions":                      models.PermViewAuditLog,
+	"/api/admin/actions/timeline":             models.PermViewAuditLog,
+	"/api/admin/stats":                        models.PermViewStats,
+	"/api/admin/invites":                      models.PermManageInvites,
+	"/api/admin/invites/delete":               models.PermManageInvites,
+	"/api/admin/keys":                         models.PermManageKeys,
+	"/api/admin/keys/revoke":                  models.PermManageKeys,
+}
+
+func TestAdminRoutePermissions(t *testing.T) {
+	routes := (&AdminHandler{}).Routes()
+
+	seen := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		if seen[route.Pattern] {
+			t.Errorf("%s is registered twice", route.Pattern)
+		}
+		seen[route.Pattern] = true
+
+		want, ok := adminRoutePermissions[route.Pattern]
+		if !ok {
+			t.Errorf("%s has no expected permission in this test", route.Pattern)
+			continue
+		}
+		if route.Permission != want {
+			t.Errorf("%s requires %s, want %s", route.Pattern, route.Permission, want)
+		}
+		if route.Handler == nil {
+			t.Errorf("%s has no handler", route.Pattern)
+		}
+	}
+
+	for pattern := range adminRoutePermissions {
+		if !seen[pattern] {
+			t.Errorf("%s is not registered", pattern)
+		}
+	}
+}
+
+func TestAdminRouteAccess(t *testing.T) {
+	allScopes := []models.Scope{models.ScopeRead, models.ScopeUpload, models.ScopeVote, models.ScopeAdmin}
+	userScopes := []models.Scope{models.ScopeRead, models.ScopeUpload, models.ScopeVote}
+
+	tests := []struct {
+		name   string
+		role   models.UserRole
+		scopes []models.Scope
+		allow  func(models.Permission) bool
+	}{
+		{"user", models.RoleUser, allScopes, func(models.Permission) bool { return false }},
+		{"moderator", models.RoleModerator, allScopes, models.RoleModerator.Can},
+		{"admin", models.RoleAdmin, allScopes, models.RoleAdmin.Can},
+		{"admin without admin scope", models.RoleAdmin, userScopes, func(models.Permission) bool { return false }},
+	}
+
+	for _, route := range (&AdminHandler{}).Routes() {
+		ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+		handler := AdminMiddleware(RequirePermission(route.Permission)(ok))
+
+		for _, tt := range tests {
+			ctx := context.WithValue(context.Background(), userContextKey, &models.User{ID: 1, Role: tt.role})
+			ctx = context.WithValue(ctx, apiKeyContextKey, &models.APIKey{ID: 1, UserID: 1, Scopes: tt.scopes})
+			req := httptest.NewRequest(http.MethodGet, route.Pattern, nil).WithContext(ctx)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			want := http.StatusForbidden
+			if tt.allow(route.Permission) {
+				want = http.StatusOK
+			}
+			if rec.Code != want {
+				t.Errorf("%s on %s: status %d, want %d", tt.name, route.Pattern, rec.Code, want)
+			}
+		}
+	}
+}
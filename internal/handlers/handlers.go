@@ -1,22 +1,86 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/quentinsteinke/mkvmender/internal/database"
+	"github.com/quentinsteinke/mkvmender/internal/logging"
+	"github.com/quentinsteinke/mkvmender/internal/metrics"
 	"github.com/quentinsteinke/mkvmender/internal/models"
+	"github.com/quentinsteinke/mkvmender/internal/naming"
+	"github.com/quentinsteinke/mkvmender/internal/pow"
+	"github.com/quentinsteinke/mkvmender/internal/ranking"
+	"github.com/quentinsteinke/mkvmender/internal/ratelimit"
+	"github.com/quentinsteinke/mkvmender/internal/username"
 )
 
+// readinessTimeout bounds the database check performed by ReadyHandler
+const readinessTimeout = 2 * time.Second
+
+// Options holds deployment-specific handler behavior
+type Options struct {
+	// RegistrationEnabled allows new accounts to be created via /api/register
+	RegistrationEnabled bool
+	// RegistrationGate requires an invite code or proof of work to register
+	RegistrationGate models.RegistrationGate
+	// ProofOfWork issues and checks challenges when RegistrationGate is
+	// GateProofOfWork
+	ProofOfWork *pow.Issuer
+	// ResetVotesOnRename clears a submission's votes when its owner changes
+	// the filename materially
+	ResetVotesOnRename bool
+	// Ranking orders submissions in lookups and searches that do not pass
+	// ?rank=
+	Ranking ranking.Method
+	// AutoHideReports hides a submission once this many users have
+	// reported it; 0 disables
+	AutoHideReports int
+	// PreModerationBelow holds submissions from users below this trust
+	// level for a moderator's approval; TrustNew holds none
+	PreModerationBelow models.TrustLevel
+	// MinDownvoteTrust is the lowest trust level that may downvote; TrustNew
+	// lets everyone. Staff may always downvote.
+	MinDownvoteTrust models.TrustLevel
+	// AccountDeletion decides whether a deleted account's submissions are
+	// kept under the ghost account or deleted with it
+	AccountDeletion models.AccountDeletionPolicy
+	// VoteLimiter is the /api/vote rate limiter, charged for the upvote an
+	// upload of an existing name casts; nil disables limiting
+	VoteLimiter *ratelimit.Limiter
+}
+
+// DefaultOptions returns the default handler options
+func DefaultOptions() Options {
+	return Options{
+		RegistrationEnabled: true,
+		RegistrationGate:    models.GateNone,
+		ResetVotesOnRename:  true,
+		Ranking:             ranking.Default,
+		AutoHideReports:     3,
+		PreModerationBelow:  models.TrustNew,
+		AccountDeletion:     models.DeletionReassign,
+	}
+}
+
 // Handler holds dependencies for HTTP handlers
 type Handler struct {
-	db *database.DB
+	db       *database.DB
+	metrics  *metrics.Metrics
+	opts     Options
+	draining atomic.Bool
 }
 
-// New creates a new Handler
-func New(db *database.DB) *Handler {
-	return &Handler{db: db}
+// New creates a new Handler; m may be nil to disable metrics
+func New(db *database.DB, m *metrics.Metrics, opts Options) *Handler {
+	return &Handler{db: db, metrics: m, opts: opts}
 }
 
 // RegisterHandler handles user registration
@@ -26,30 +90,89 @@ func (h *Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		Username string `json:"username"`
+	if !h.opts.RegistrationEnabled {
+		respondError(w, http.StatusForbidden, "registration is disabled on this server")
+		return
 	}
 
+	var req models.RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
+	req.Username = strings.TrimSpace(req.Username)
 	if req.Username == "" {
-		respondError(w, http.StatusBadRequest, "username is required")
+		respondErrorCode(w, http.StatusBadRequest, models.ErrorCodeInvalidUsername, "username is required")
+		return
+	}
+	if err := username.Validate(req.Username); err != nil {
+		respondErrorCode(w, http.StatusBadRequest, models.ErrorCodeInvalidUsername, err.Error())
 		return
 	}
 
+	inviteCode := ""
+	switch h.opts.RegistrationGate {
+	case models.GateInvite:
+		inviteCode = strings.TrimSpace(req.InviteCode)
+		if inviteCode == "" {
+			respondErrorCode(w, http.StatusForbidden, models.ErrorCodeInviteRequired, "an invite code is required to register on this server")
+			return
+		}
+	case models.GateProofOfWork:
+		if req.Challenge == "" || req.Nonce == "" {
+			respondErrorCode(w, http.StatusForbidden, models.ErrorCodeProofOfWorkRequired, "a solved challenge from /api/register/challenge is required")
+			return
+		}
+		if err := h.opts.ProofOfWork.Verify(req.Challenge, req.Nonce); err != nil {
+			respondErrorCode(w, http.StatusForbidden, models.ErrorCodeProofOfWorkRequired, err.Error())
+			return
+		}
+	}
+
 	// Create user
-	user, err := h.db.CreateUser(req.Username)
+	user, err := h.db.CreateUser(req.Username, inviteCode)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to create user")
+		switch {
+		case errors.Is(err, database.ErrUsernameTaken):
+			respondErrorCode(w, http.StatusConflict, models.ErrorCodeUsernameTaken, err.Error())
+		case errors.Is(err, database.ErrInvalidInvite):
+			respondErrorCode(w, http.StatusForbidden, models.ErrorCodeInvalidInvite, err.Error())
+		default:
+			respondInternalError(w, r, "failed to create user", err)
+		}
 		return
 	}
 
 	respondJSON(w, http.StatusCreated, user)
 }
 
+// RegistrationChallengeHandler issues a proof-of-work challenge for
+// registration on servers that require one
+func (h *Handler) RegistrationChallengeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if !h.opts.RegistrationEnabled || h.opts.RegistrationGate != models.GateProofOfWork {
+		respondError(w, http.StatusNotFound, "this server does not use registration challenges")
+		return
+	}
+
+	challenge, expiresAt, err := h.opts.ProofOfWork.Issue()
+	if err != nil {
+		respondInternalError(w, r, "failed to issue challenge", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.RegistrationChallenge{
+		Challenge:  challenge,
+		Difficulty: h.opts.ProofOfWork.Difficulty(),
+		ExpiresAt:  expiresAt,
+	})
+}
+
 // LookupHandler handles file hash lookup
 func (h *Handler) LookupHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -64,11 +187,19 @@ func (h *Handler) LookupHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	rank, err := h.rankingFor(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Get file hash info
 	fileHash, err := h.db.GetFileHashByHash(hash)
 	if err != nil {
+		h.metrics.Lookup(false)
 		respondJSON(w, http.StatusOK, models.HashLookupResponse{
 			Hash:        hash,
+			Ranking:     string(rank),
 			Submissions: []models.SubmissionWithVotes{},
 		})
 		return
@@ -77,22 +208,47 @@ func (h *Handler) LookupHandler(w http.ResponseWriter, r *http.Request) {
 	// Get submissions for this hash
 	submissions, err := h.db.GetSubmissionsByHash(hash)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to get submissions")
+		respondInternalError(w, r, "failed to get submissions", err)
 		return
 	}
+	h.metrics.Lookup(len(submissions) > 0)
+	rank.Rank(submissions, time.Now())
 
 	// Get metadata for each submission
 	for i := range submissions {
 		meta, err := h.db.GetMetadataBySubmissionID(submissions[i].ID)
-		if err == nil && meta != nil {
+		if err != nil {
+			logging.FromContext(r.Context()).Warn("failed to get metadata",
+				"submission_id", submissions[i].ID, "error", err)
+			continue
+		}
+		if meta != nil {
 			submissions[i].Metadata = meta
 		}
 	}
 
+	// Tell signed-in callers how they voted
+	if user, ok := GetUserFromContext(r.Context()); ok {
+		ids := make([]int64, len(submissions))
+		for i := range submissions {
+			ids[i] = submissions[i].ID
+		}
+		votes, err := h.db.GetUserVotes(user.ID, ids)
+		if err != nil {
+			respondInternalError(w, r, "failed to get votes", err)
+			return
+		}
+		for i := range submissions {
+			vote := votes[submissions[i].ID]
+			submissions[i].MyVote = &vote
+		}
+	}
+
 	response := models.HashLookupResponse{
 		Hash:        fileHash.Hash,
 		FileSize:    fileHash.FileSize,
 		MediaType:   fileHash.MediaType,
+		Ranking:     string(rank),
 		Submissions: submissions,
 	}
 
@@ -124,6 +280,10 @@ func (h *Handler) UploadHandler(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "hash and filename are required")
 		return
 	}
+	if err := naming.CheckFilename(req.Filename); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	if req.MediaType != models.MediaTypeMovie && req.MediaType != models.MediaTypeTV {
 		respondError(w, http.StatusBadRequest, "invalid media type")
@@ -133,27 +293,76 @@ func (h *Handler) UploadHandler(w http.ResponseWriter, r *http.Request) {
 	// Create or get file hash
 	fileHash, err := h.db.CreateFileHash(req.Hash, req.FileSize, req.MediaType)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to create file hash")
+		respondInternalError(w, r, "failed to create file hash", err)
+		return
+	}
+
+	// An identical name already submitted for this file gets the uploader's
+	// upvote instead of a second submission that would split the votes.
+	// Only keys that may vote cast it, within the /api/vote rate limit.
+	// Other users' submissions held for approval or hidden by reports do
+	// not count as duplicates, so the upload is created alongside them.
+	duplicate, err := h.db.FindDuplicateSubmission(fileHash.ID, req.Filename, user.ID, 0)
+	if err != nil {
+		respondInternalError(w, r, "failed to check for duplicates", err)
 		return
 	}
+	if duplicate != nil {
+		if duplicate.UserID != user.ID && h.mayVote(r) {
+			if err := h.db.CreateOrUpdateVote(duplicate.ID, user.ID, models.VoteUp, user.TrustLevel.VoteWeight()); err != nil {
+				respondInternalError(w, r, "failed to create vote", err)
+				return
+			}
+			h.metrics.Vote(models.VoteUp)
+		}
 
-	// Create submission
-	submission, err := h.db.CreateSubmission(fileHash.ID, user.ID, req.Filename)
+		respondJSON(w, http.StatusOK, models.UploadResponse{
+			NamingSubmission: *duplicate,
+			DuplicateOf:      &duplicate.ID,
+		})
+		return
+	}
+
+	// Create submission, held for approval if the uploader is not trusted
+	// enough yet
+	pending := !user.Role.IsStaff() && user.TrustLevel < h.opts.PreModerationBelow
+	submission, err := h.db.CreateSubmission(fileHash.ID, user.ID, req.Filename, pending)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to create submission")
+		respondInternalError(w, r, "failed to create submission", err)
 		return
 	}
+	h.metrics.Upload()
 
 	// Create metadata if provided
 	if req.Metadata != nil {
 		req.Metadata.SubmissionID = submission.ID
 		if err := h.db.CreateMetadata(req.Metadata); err != nil {
 			// Log error but don't fail the request
-			// TODO: Add proper logging
+			logging.FromContext(r.Context()).Error("failed to create metadata",
+				"submission_id", submission.ID, "hash", req.Hash, "error", err)
 		}
 	}
 
-	respondJSON(w, http.StatusCreated, submission)
+	if err := h.db.RecordOriginalRevision(submission.ID); err != nil {
+		logging.FromContext(r.Context()).Error("failed to record original revision",
+			"submission_id", submission.ID, "error", err)
+	}
+
+	respondJSON(w, http.StatusCreated, models.UploadResponse{NamingSubmission: *submission})
+}
+
+// mayVote reports whether the caller's key has the vote scope and, when
+// Options.VoteLimiter is set, charges it for a vote cast on the caller's
+// behalf
+func (h *Handler) mayVote(r *http.Request) bool {
+	key, ok := GetAPIKeyFromContext(r.Context())
+	if !ok || !key.HasScope(models.ScopeVote) {
+		return false
+	}
+	if h.opts.VoteLimiter == nil {
+		return true
+	}
+	return h.opts.VoteLimiter.Allow(RateLimitByAPIKey(r)).Allowed
 }
 
 // VoteHandler handles voting on submissions
@@ -182,16 +391,30 @@ func (h *Handler) VoteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create or update vote
-	if err := h.db.CreateOrUpdateVote(req.SubmissionID, user.ID, req.VoteType); err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to create vote")
+	if req.VoteType == models.VoteDown && !user.Role.IsStaff() && user.TrustLevel < h.opts.MinDownvoteTrust {
+		respondErrorCode(w, http.StatusForbidden, models.ErrorCodeInsufficientTrust,
+			fmt.Sprintf("downvoting requires trust level %s", h.opts.MinDownvoteTrust))
+		return
+	}
+
+	// Create or update vote, weighted by the voter's trust level
+	if err := h.db.CreateOrUpdateVote(req.SubmissionID, user.ID, req.VoteType, user.TrustLevel.VoteWeight()); err != nil {
+		switch {
+		case errors.Is(err, database.ErrSelfVote):
+			respondError(w, http.StatusForbidden, err.Error())
+		case err.Error() == "submission not found":
+			respondError(w, http.StatusNotFound, "submission not found")
+		default:
+			respondInternalError(w, r, "failed to create vote", err)
+		}
 		return
 	}
+	h.metrics.Vote(req.VoteType)
 
 	// Get updated submission with vote counts
 	submission, err := h.db.GetSubmissionByID(req.SubmissionID)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to get submission")
+		respondInternalError(w, r, "failed to get submission", err)
 		return
 	}
 
@@ -236,9 +459,14 @@ func (h *Handler) DeleteVoteHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Delete vote
 	if err := h.db.DeleteVote(submissionID, user.ID); err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to delete vote")
+		if err.Error() == "vote not found" {
+			respondError(w, http.StatusNotFound, "vote not found")
+			return
+		}
+		respondInternalError(w, r, "failed to delete vote", err)
 		return
 	}
+	h.metrics.VoteDeleted()
 
 	respondSuccess(w, "vote removed")
 }
@@ -277,19 +505,27 @@ func (h *Handler) SearchHandler(w http.ResponseWriter, r *http.Request) {
 		sortBy = database.SortByRelevance
 	}
 
+	rank, err := h.rankingFor(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Get fuzzy parameter (default: true)
 	useFuzzy := r.URL.Query().Get("fuzzy") != "false"
 
 	// Search database
 	dbResults, err := h.db.SearchByTitle(query, sortBy, useFuzzy)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "search failed")
+		respondInternalError(w, r, "search failed", err)
 		return
 	}
 
 	// Convert database results to API results
+	now := time.Now()
 	var results []models.SearchResult
 	for _, dbResult := range dbResults {
+		rank.Rank(dbResult.Submissions, now)
 		result := models.SearchResult{
 			Title:       dbResult.Title,
 			Year:        dbResult.Year,
@@ -305,6 +541,7 @@ func (h *Handler) SearchHandler(w http.ResponseWriter, r *http.Request) {
 
 	response := models.SearchResponse{
 		Query:   query,
+		Ranking: string(rank),
 		Results: results,
 	}
 
@@ -316,6 +553,38 @@ func (h *Handler) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// ReadyHandler reports whether the server can serve traffic: it fails while
+// the server is draining for shutdown or when the database is unreachable
+func (h *Handler) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		respondJSON(w, http.StatusServiceUnavailable, map[string]string{
+			"status": "unavailable",
+			"reason": "shutting down",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	if err := h.db.Ping(ctx); err != nil {
+		logging.FromContext(r.Context()).Warn("readiness check failed", "error", err)
+		respondJSON(w, http.StatusServiceUnavailable, map[string]string{
+			"status": "unavailable",
+			"reason": "database unreachable",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// StartDraining marks the server as shutting down so ReadyHandler fails and
+// load balancers stop routing new requests here
+func (h *Handler) StartDraining() {
+	h.draining.Store(true)
+}
+
 // VerifyHandler handles API key verification and returns user info
 func (h *Handler) VerifyHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -332,11 +601,36 @@ func (h *Handler) VerifyHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Return user info (without API key)
 	response := map[string]interface{}{
-		"id":       user.ID,
-		"username": user.Username,
-		"role":     user.Role,
-		"is_active": user.IsActive,
+		"id":          user.ID,
+		"username":    user.Username,
+		"role":        user.Role,
+		"is_active":   user.IsActive,
+		"reputation":  user.Reputation,
+		"trust_level": user.TrustLevel,
+	}
+
+	// Describe the key used, so clients can tell what it may do
+	if key, ok := GetAPIKeyFromContext(r.Context()); ok {
+		response["key"] = map[string]interface{}{
+			"id":     key.ID,
+			"name":   key.Name,
+			"prefix": key.Prefix,
+		}
+		response["scopes"] = key.Scopes
+	}
+	if user.Role.IsStaff() {
+		response["permissions"] = models.RolePermissions[user.Role]
 	}
 
 	respondJSON(w, http.StatusOK, response)
 }
+
+// rankingFor returns the ranking requested with ?rank=, or the server's
+// default
+func (h *Handler) rankingFor(r *http.Request) (ranking.Method, error) {
+	name := r.URL.Query().Get("rank")
+	if name == "" {
+		return h.opts.Ranking, nil
+	}
+	return ranking.Parse(name)
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/quentinsteinke/mkvmender/internal/models"
+)
+
+const (
+	// maxAPIKeysPerUser caps how many keys one account may hold
+	maxAPIKeysPerUser = 20
+	// maxAPIKeyNameLength caps the length of a key name
+	maxAPIKeyNameLength = 64
+)
+
+// KeysHandler lists (GET) or creates (POST) the caller's API keys
+func (h *Handler) KeysHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.listKeys(w, r)
+	case http.MethodPost:
+		h.createKey(w, r)
+	default:
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// listKeys returns the caller's API keys, marking the one in use
+func (h *Handler) listKeys(w http.ResponseWriter, r *http.Request) {
+	user, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	current, ok := GetAPIKeyFromContext(r.Context())
+	if !ok || !current.HasScope(models.ScopeRead) {
+		respondError(w, http.StatusForbidden, fmt.Sprintf("API key lacks the %q scope", models.ScopeRead))
+		return
+	}
+
+	keys, err := h.db.ListAPIKeys(user.ID)
+	if err != nil {
+		respondInternalError(w, r, "failed to list API keys", err)
+		return
+	}
+
+	for i := range keys {
+		keys[i].Current = keys[i].ID == current.ID
+	}
+
+	respondJSON(w, http.StatusOK, models.APIKeyListResponse{Keys: keys})
+}
+
+// createKey issues a new named API key for the caller. A key can only
+// grant scopes it may grant itself (see canGrant).
+func (h *Handler) createKey(w http.ResponseWriter, r *http.Request) {
+	user, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	current, ok := GetAPIKeyFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		respondError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if len(name) > maxAPIKeyNameLength {
+		respondError(w, http.StatusBadRequest, "name is too long")
+		return
+	}
+
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = models.DefaultScopes
+	}
+	for _, scope := range scopes {
+		if !models.ValidScope(scope) {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("unknown scope %q", scope))
+			return
+		}
+		if scope == models.ScopeAdmin && !user.Role.IsStaff() {
+			respondError(w, http.StatusForbidden, "the admin scope requires an admin or moderator account")
+			return
+		}
+		if !canGrant(current, scope) {
+			respondError(w, http.StatusForbidden, fmt.Sprintf("API key lacks the %q scope it is trying to grant", scope))
+			return
+		}
+	}
+
+	keys, err := h.db.ListAPIKeys(user.ID)
+	if err != nil {
+		respondInternalError(w, r, "failed to list API keys", err)
+		return
+	}
+	if len(keys) >= maxAPIKeysPerUser {
+		respondError(w, http.StatusConflict, "API key limit reached; revoke an unused key first")
+		return
+	}
+	for _, key := range keys {
+		if key.Name == name {
+			respondError(w, http.StatusConflict, "an API key with this name already exists")
+			return
+		}
+	}
+
+	key, err := h.db.CreateAPIKey(user.ID, name, scopes)
+	if err != nil {
+		respondInternalError(w, r, "failed to create API key", err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, key)
+}
+
+// DeleteKeyHandler revokes one of the caller's API keys. The last remaining
+// key cannot be revoked, since the account would be locked out.
+func (h *Handler) DeleteKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	keyID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid key id")
+		return
+	}
+
+	keys, ok := h.ownedKeys(w, r, user.ID, keyID)
+	if !ok {
+		return
+	}
+	if len(keys) == 1 {
+		respondError(w, http.StatusConflict, "cannot revoke your only API key; create another key first")
+		return
+	}
+
+	if err := h.db.DeleteAPIKey(user.ID, keyID); err != nil {
+		respondInternalError(w, r, "failed to revoke API key", err)
+		return
+	}
+
+	respondSuccess(w, "api key revoked")
+}
+
+// RotateKeyHandler replaces the secret of one of the caller's API keys
+func (h *Handler) RotateKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	keyID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid key id")
+		return
+	}
+
+	if _, ok := h.ownedKeys(w, r, user.ID, keyID); !ok {
+		return
+	}
+
+	key, err := h.db.RotateAPIKey(user.ID, keyID)
+	if err != nil {
+		respondInternalError(w, r, "failed to rotate API key", err)
+		return
+	}
+
+	if current, ok := GetAPIKeyFromContext(r.Context()); ok {
+		key.Current = key.ID == current.ID
+	}
+
+	respondJSON(w, http.StatusOK, key)
+}
+
+// ownedKeys loads the user's API keys and checks that keyID is one of them
+// and grants no scope the calling key lacks, writing an error response and
+// returning false otherwise
+func (h *Handler) ownedKeys(w http.ResponseWriter, r *http.Request, userID, keyID int64) ([]models.APIKey, bool) {
+	current, ok := GetAPIKeyFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return nil, false
+	}
+
+	keys, err := h.db.ListAPIKeys(userID)
+	if err != nil {
+		respondInternalError(w, r, "failed to list API keys", err)
+		return nil, false
+	}
+
+	for _, key := range keys {
+		if key.ID != keyID {
+			continue
+		}
+		for _, scope := range key.Scopes {
+			if !canGrant(current, scope) {
+				respondError(w, http.StatusForbidden, fmt.Sprintf("API key lacks the %q scope held by the target key", scope))
+				return nil, false
+			}
+		}
+		return keys, true
+	}
+
+	respondError(w, http.StatusNotFound, "api key not found")
+	return nil, false
+}
+
+// canGrant reports whether a request authenticated with key may hand out
+// scope, either by creating a key or by rotating one. Keys grant only scopes
+// they hold, except that a key with every default scope may grant admin so
+// newly promoted staff can obtain an admin key; callers check the role.
+func canGrant(key *models.APIKey, scope models.Scope) bool {
+	if key.HasScope(scope) {
+		return true
+	}
+	if scope != models.ScopeAdmin {
+		return false
+	}
+	for _, s := range models.DefaultScopes {
+		if !key.HasScope(s) {
+			return false
+		}
+	}
+	return true
+}
@@ -2,74 +2,191 @@ package handlers
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/quentinsteinke/mkvmender/internal/database"
+	"github.com/quentinsteinke/mkvmender/internal/logging"
+	"github.com/quentinsteinke/mkvmender/internal/metrics"
 	"github.com/quentinsteinke/mkvmender/internal/models"
+	"github.com/quentinsteinke/mkvmender/internal/ratelimit"
 )
 
 type contextKey string
 
-const userContextKey contextKey = "user"
+const (
+	userContextKey        contextKey = "user"
+	apiKeyContextKey      contextKey = "api_key"
+	requestInfoContextKey contextKey = "request_info"
+)
+
+// RequestIDHeader is the header used to propagate request IDs
+const RequestIDHeader = "X-Request-ID"
+
+// requestInfo collects per-request details filled in by inner middleware
+type requestInfo struct {
+	userID int64
+}
 
-// AuthMiddleware validates API key and adds user to context
-func AuthMiddleware(db *database.DB) func(http.Handler) http.Handler {
+// AuthMiddleware validates API key and adds user to context. The key must
+// grant every scope in required.
+func AuthMiddleware(db *database.DB, required ...models.Scope) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Get API key from Authorization header
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
+			if r.Header.Get("Authorization") == "" {
 				respondError(w, http.StatusUnauthorized, "missing authorization header")
 				return
 			}
 
-			// Expected format: "Bearer <api_key>"
-			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || parts[0] != "Bearer" {
+			apiKey, ok := bearerToken(r)
+			if !ok {
 				respondError(w, http.StatusUnauthorized, "invalid authorization header format")
 				return
 			}
 
-			apiKey := parts[1]
-
 			// Validate API key
-			user, err := db.GetUserByAPIKey(apiKey)
+			user, key, err := db.AuthenticateAPIKey(apiKey)
 			if err != nil {
 				respondError(w, http.StatusUnauthorized, "invalid API key")
 				return
 			}
 
-			// Add user to context
+			// Suspended accounts are locked out until an admin reactivates
+			// them or a temporary suspension runs out
+			if !user.IsActive {
+				if !reactivateIfExpired(r, db, user) {
+					message := "account suspended"
+					if user.SuspendedUntil != nil {
+						message = fmt.Sprintf("account suspended until %s", user.SuspendedUntil.UTC().Format(time.RFC3339))
+					}
+					respondErrorCode(w, http.StatusForbidden, models.ErrorCodeAccountSuspended, message)
+					return
+				}
+			}
+
+			for _, scope := range required {
+				if !key.HasScope(scope) {
+					respondError(w, http.StatusForbidden, fmt.Sprintf("API key lacks the %q scope", scope))
+					return
+				}
+			}
+
+			// Record user for request logging
+			if info, ok := r.Context().Value(requestInfoContextKey).(*requestInfo); ok {
+				info.userID = user.ID
+			}
+
+			// Add user, key and user-scoped logger to context
 			ctx := context.WithValue(r.Context(), userContextKey, user)
+			ctx = context.WithValue(ctx, apiKeyContextKey, key)
+			ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("user_id", user.ID))
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// OptionalAuthMiddleware authenticates requests that carry an Authorization
+// header, as AuthMiddleware does, and lets anonymous requests through, so
+// public endpoints can add details for signed-in callers
+func OptionalAuthMiddleware(db *database.DB, required ...models.Scope) func(http.Handler) http.Handler {
+	auth := AuthMiddleware(db, required...)
+	return func(next http.Handler) http.Handler {
+		authenticated := auth(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			authenticated.ServeHTTP(w, r)
+		})
+	}
+}
+
+// reactivateIfExpired lifts an expired temporary suspension, reporting
+// whether the user is active again
+func reactivateIfExpired(r *http.Request, db *database.DB, user *models.User) bool {
+	if user.SuspendedUntil == nil || time.Now().Before(*user.SuspendedUntil) {
+		return false
+	}
+
+	reactivated, err := db.ReactivateUser(user.ID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to reactivate user", "user_id", user.ID, "error", err)
+		return false
+	}
+	if reactivated {
+		logging.FromContext(r.Context()).Info("suspension expired, user reactivated", "user_id", user.ID)
+	}
+
+	user.IsActive = true
+	user.SuspendedUntil = nil
+	return true
+}
+
+// bearerToken extracts the API key from an "Authorization: Bearer <api_key>" header
+func bearerToken(r *http.Request) (string, bool) {
+	parts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(parts) != 2 || parts[0] != "Bearer" || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
 // GetUserFromContext retrieves the authenticated user from context
 func GetUserFromContext(ctx context.Context) (*models.User, bool) {
 	user, ok := ctx.Value(userContextKey).(*models.User)
 	return user, ok
 }
 
-// CORSMiddleware adds CORS headers
-func CORSMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+// GetAPIKeyFromContext retrieves the API key used to authenticate the request
+func GetAPIKeyFromContext(ctx context.Context) (*models.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey).(*models.APIKey)
+	return key, ok
+}
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
+// CORSMiddleware adds CORS headers for the allowed origins; "*" allows any
+func CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
+	allowAny := false
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAny = true
 		}
+		allowed[origin] = true
+	}
 
-		next.ServeHTTP(w, r)
-	})
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if allowAny {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Add("Vary", "Origin")
+				if origin != "" && allowed[origin] {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+				}
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+			w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
+
+			if r.Method == "OPTIONS" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
-// AdminMiddleware checks if user has admin or moderator role
+// AdminMiddleware checks if user has admin or moderator role and that the
+// API key carries the admin scope
 // Must be used after AuthMiddleware
 func AdminMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -79,20 +196,203 @@ func AdminMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// Check if user has admin or moderator role
-		if user.Role != models.RoleAdmin && user.Role != models.RoleModerator {
+		// Check if user has a staff role
+		if !user.Role.IsStaff() {
 			respondError(w, http.StatusForbidden, "insufficient permissions")
 			return
 		}
 
+		// Admin powers must be granted to the key explicitly
+		if key, ok := GetAPIKeyFromContext(r.Context()); !ok || !key.HasScope(models.ScopeAdmin) {
+			respondError(w, http.StatusForbidden, fmt.Sprintf("API key lacks the %q scope", models.ScopeAdmin))
+			return
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
 
-// LoggingMiddleware logs HTTP requests
-func LoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Add proper logging
-		next.ServeHTTP(w, r)
-	})
+// RequirePermission rejects users whose role lacks perm in the permission
+// matrix (models.RolePermissions)
+// Must be used after AdminMiddleware
+func RequirePermission(perm models.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := GetUserFromContext(r.Context())
+			if !ok {
+				respondError(w, http.StatusUnauthorized, "authentication required")
+				return
+			}
+
+			if !user.Role.Can(perm) {
+				respondError(w, http.StatusForbidden, fmt.Sprintf("the %s role lacks the %q permission", user.Role, perm))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LoggingMiddleware assigns a request ID and logs each HTTP request
+func LoggingMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			// Reuse the caller's request ID if it is well-formed
+			requestID := r.Header.Get(RequestIDHeader)
+			if !logging.ValidRequestID(requestID) {
+				requestID = logging.NewRequestID()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			info := &requestInfo{}
+			reqLogger := logger.With("request_id", requestID)
+
+			ctx := logging.WithRequestID(r.Context(), requestID)
+			ctx = logging.WithLogger(ctx, reqLogger)
+			ctx = context.WithValue(ctx, requestInfoContextKey, info)
+			r = r.WithContext(ctx)
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			route := routeOf(r)
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("route", route),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rec.status),
+				slog.Int64("bytes", rec.bytes),
+				slog.Duration("latency", time.Since(start)),
+				slog.String("remote_addr", r.RemoteAddr),
+			}
+			if info.userID != 0 {
+				attrs = append(attrs, slog.Int64("user_id", info.userID))
+			}
+
+			level := slog.LevelInfo
+			if rec.status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			reqLogger.LogAttrs(ctx, level, "request completed", attrs...)
+		})
+	}
+}
+
+// MetricsMiddleware records request counts and latency per route and status
+func MetricsMiddleware(m *metrics.Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			m.ObserveRequest(routeOf(r), r.Method, rec.status, time.Since(start))
+		})
+	}
+}
+
+// MaxBodyMiddleware rejects request bodies larger than maxBytes
+func MaxBodyMiddleware(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				respondError(w, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RateLimitKeyFunc derives the rate limiting key for a request
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitByAPIKey keys requests by their bearer API key
+func RateLimitByAPIKey(r *http.Request) string {
+	apiKey, _ := bearerToken(r)
+	return "key:" + apiKey
+}
+
+// RateLimitByIP keys requests by client IP as resolved by limits
+func RateLimitByIP(limits *ratelimit.Set) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		return "ip:" + limits.ClientIP(r)
+	}
+}
+
+// RateLimitMiddleware enforces limiter per key, setting RateLimit-* headers on
+// every response and Retry-After when the limit is exceeded. A nil limiter
+// disables limiting.
+func RateLimitMiddleware(limiter *ratelimit.Limiter, keyFunc RateLimitKeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result := limiter.Allow(keyFunc(r))
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+			if !result.Allowed {
+				retryAfter := ceilSeconds(result.RetryAfter)
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				logging.FromContext(r.Context()).Warn("rate limit exceeded",
+					"route", r.URL.Path, "retry_after", retryAfter)
+				respondError(w, http.StatusTooManyRequests,
+					fmt.Sprintf("rate limit exceeded, retry in %d seconds", retryAfter))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// routeOf returns the ServeMux pattern that served r. ServeMux records the
+// matched pattern on the request it was given, so this must be called after
+// the request has been dispatched.
+func routeOf(r *http.Request) string {
+	if r.Pattern == "" {
+		return "unmatched"
+	}
+	return r.Pattern
+}
+
+// statusRecorder captures the status code and size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
 }
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/quentinsteinke/mkvmender/internal/metrics"
+)
+
+// scrape returns the text exposition of m's registry
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("scrape returned %d", rec.Code)
+	}
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatalf("failed to read scrape: %v", err)
+	}
+	return string(body)
+}
+
+func TestMetricsMiddlewareRecordsRoutePattern(t *testing.T) {
+	m := metrics.New(nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/submissions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "404" {
+			respondError(w, http.StatusNotFound, "submission not found")
+			return
+		}
+		respondSuccess(w, "ok")
+	})
+	server := httptest.NewServer(MetricsMiddleware(m)(mux))
+	defer server.Close()
+
+	for _, path := range []string{"/api/submissions/1", "/api/submissions/2", "/api/submissions/404", "/nowhere"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`mkvmender_http_requests_total{method="GET",route="/api/submissions/{id}",status="200"} 2`,
+		`mkvmender_http_requests_total{method="GET",route="/api/submissions/{id}",status="404"} 1`,
+		`mkvmender_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`mkvmender_http_request_duration_seconds_count{method="GET",route="/api/submissions/{id}",status="200"} 2`,
+		`mkvmender_http_request_duration_seconds_bucket{method="GET",route="/api/submissions/{id}",status="404",le="+Inf"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("scrape is missing %q", want)
+		}
+	}
+	if strings.Contains(body, `route="/api/submissions/1"`) {
+		t.Error("requests are labelled by path instead of route pattern")
+	}
+}
+
+func TestMetricsMiddlewareNilMetrics(t *testing.T) {
+	handler := MetricsMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
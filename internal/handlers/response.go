@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/quentinsteinke/mkvmender/internal/logging"
 	"github.com/quentinsteinke/mkvmender/internal/models"
 )
 
@@ -24,6 +25,21 @@ func respondError(w http.ResponseWriter, status int, message string) {
 	})
 }
 
+// respondErrorCode sends a JSON error response carrying a machine-readable code
+func respondErrorCode(w http.ResponseWriter, status int, code, message string) {
+	respondJSON(w, status, models.ErrorResponse{
+		Error:   http.StatusText(status),
+		Code:    code,
+		Message: message,
+	})
+}
+
+// respondInternalError logs err with the request context and sends a 500 response
+func respondInternalError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	logging.FromContext(r.Context()).Error(message, "error", err)
+	respondError(w, http.StatusInternalServerError, message)
+}
+
 // respondSuccess sends a JSON success response
 func respondSuccess(w http.ResponseWriter, message string) {
 	respondJSON(w, http.StatusOK, models.SuccessResponse{
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quentinsteinke/mkvmender/internal/database"
+	"github.com/quentinsteinke/mkvmender/internal/logging"
+	"github.com/quentinsteinke/mkvmender/internal/models"
+	"github.com/quentinsteinke/mkvmender/internal/naming"
+)
+
+// MySubmissionsHandler lists the caller's own submissions
+// GET /api/me/submissions
+func (h *Handler) MySubmissionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	submissions, err := h.db.GetSubmissionsByUser(user.ID)
+	if err != nil {
+		respondInternalError(w, r, "failed to get submissions", err)
+		return
+	}
+	now := time.Now()
+	for i := range submissions {
+		submissions[i].Confidence = h.opts.Ranking.Confidence(&submissions[i], now)
+	}
+
+	respondJSON(w, http.StatusOK, submissions)
+}
+
+// SubmissionHandler lets owners edit (PUT) or retract (DELETE) their
+// submissions
+// PUT/DELETE /api/submissions/{id}
+func (h *Handler) SubmissionHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPut:
+		h.updateSubmission(w, r)
+	case http.MethodDelete:
+		h.retractSubmission(w, r)
+	default:
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// SubmissionHistoryHandler lists a submission's revisions, newest first.
+// Pending and hidden submissions are only visible to their owner and staff,
+// as they are left out of lookups and searches.
+// GET /api/submissions/{id}/history
+func (h *Handler) SubmissionHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	submissionID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid submission ID")
+		return
+	}
+
+	submission, err := h.db.GetSubmissionByID(submissionID)
+	if err != nil || !canSeeSubmission(r, submission) {
+		respondError(w, http.StatusNotFound, "submission not found")
+		return
+	}
+
+	revisions, err := h.db.GetSubmissionRevisions(submissionID)
+	if err != nil {
+		respondInternalError(w, r, "failed to get submission history", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, revisions)
+}
+
+// canSeeSubmission reports whether the caller may see a submission: anyone
+// may see published ones, but pending and hidden submissions are limited to
+// their owner and staff using a key with the admin scope
+func canSeeSubmission(r *http.Request, submission *models.SubmissionWithVotes) bool {
+	if !submission.Pending && !submission.Hidden {
+		return true
+	}
+
+	user, ok := GetUserFromContext(r.Context())
+	if !ok {
+		return false
+	}
+	if user.ID == submission.UserID {
+		return true
+	}
+
+	key, ok := GetAPIKeyFromContext(r.Context())
+	return ok && key.HasScope(models.ScopeAdmin) && user.Role.Can(models.PermViewSubmissions)
+}
+
+// maxReportDetails caps the length of a report's free-text details
+const maxReportDetails = 1000
+
+// ReportSubmissionHandler lets users report a bad submission to moderators.
+// Enough reports hide the submission until a moderator resolves them.
+// POST /api/submissions/{id}/reports
+func (h *Handler) ReportSubmissionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	submissionID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid submission ID")
+		return
+	}
+
+	var req models.CreateReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !models.ValidReportReason(req.Reason) {
+		respondError(w, http.StatusBadRequest, "reason must be wrong_title, wrong_episode, spam or offensive")
+		return
+	}
+	if req.Details != nil {
+		details := strings.TrimSpace(*req.Details)
+		if len(details) > maxReportDetails {
+			respondError(w, http.StatusBadRequest, "details are too long")
+			return
+		}
+		req.Details = &details
+		if details == "" {
+			req.Details = nil
+		}
+	}
+
+	// Submissions held for approval or already hidden are not reportable by
+	// those who cannot see them, and do not reveal that they exist
+	submission, err := h.db.GetSubmissionByID(submissionID)
+	if err != nil || !canSeeSubmission(r, submission) {
+		respondError(w, http.StatusNotFound, "submission not found")
+		return
+	}
+	if submission.UserID == user.ID {
+		respondError(w, http.StatusForbidden, "you cannot report your own submission")
+		return
+	}
+
+	reportID, hidden, err := h.db.CreateReport(submissionID, user.ID, req.Reason, req.Details, h.opts.AutoHideReports)
+	if err != nil {
+		if errors.Is(err, database.ErrAlreadyReported) {
+			respondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		respondInternalError(w, r, "failed to report submission", err)
+		return
+	}
+	if hidden {
+		logging.FromContext(r.Context()).Info("submission hidden after reports",
+			"submission_id", submissionID, "reports", h.opts.AutoHideReports)
+	}
+
+	report, err := h.db.GetReport(reportID)
+	if err != nil {
+		respondInternalError(w, r, "failed to get report", err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, models.CreateReportResponse{
+		Report: *report,
+		Hidden: hidden,
+	})
+}
+
+// updateSubmission applies an owner's edit, resetting votes when the
+// filename changed materially and the server is configured to do so
+func (h *Handler) updateSubmission(w http.ResponseWriter, r *http.Request) {
+	user, submission, ok := h.ownedSubmission(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateSubmissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	filename := strings.TrimSpace(req.Filename)
+	if filename == "" && req.Metadata == nil {
+		respondError(w, http.StatusBadRequest, "filename or metadata is required")
+		return
+	}
+	if filename == "" {
+		filename = submission.Filename
+	}
+	if err := naming.CheckFilename(filename); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Renaming onto another submission's name would split the votes
+	// between two entries for the same name, as an upload would
+	if filename != submission.Filename {
+		duplicate, err := h.db.FindDuplicateSubmission(submission.HashID, filename, user.ID, submission.ID)
+		if err != nil {
+			respondInternalError(w, r, "failed to check for duplicates", err)
+			return
+		}
+		if duplicate != nil {
+			respondError(w, http.StatusConflict, fmt.Sprintf("submission %d already has this name", duplicate.ID))
+			return
+		}
+	}
+
+	// Votes cast for one name say nothing about a materially different one
+	resetVotes := h.opts.ResetVotesOnRename && !naming.Same(submission.Filename, filename)
+	if err := h.db.UpdateSubmission(submission.ID, user.ID, filename, req.Metadata, req.Reason, resetVotes); err != nil {
+		respondInternalError(w, r, "failed to update submission", err)
+		return
+	}
+
+	updated, err := h.db.GetSubmissionByID(submission.ID)
+	if err != nil {
+		respondInternalError(w, r, "failed to get submission", err)
+		return
+	}
+	if updated.Metadata, err = h.db.GetMetadataBySubmissionID(submission.ID); err != nil {
+		respondInternalError(w, r, "failed to get metadata", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.UpdateSubmissionResponse{
+		Submission: *updated,
+		VotesReset: resetVotes,
+	})
+}
+
+// retractSubmission deletes one of the caller's submissions
+func (h *Handler) retractSubmission(w http.ResponseWriter, r *http.Request) {
+	user, submission, ok := h.ownedSubmission(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteSubmission(submission.ID, user.ID); err != nil {
+		respondInternalError(w, r, "failed to retract submission", err)
+		return
+	}
+
+	respondSuccess(w, "submission retracted")
+}
+
+// ownedSubmission loads the submission named by the {id} path value and
+// checks that the caller uploaded it, writing an error response and
+// returning false otherwise
+func (h *Handler) ownedSubmission(w http.ResponseWriter, r *http.Request) (*models.User, *models.SubmissionWithVotes, bool) {
+	user, ok := GetUserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "authentication required")
+		return nil, nil, false
+	}
+
+	submissionID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid submission ID")
+		return nil, nil, false
+	}
+
+	submission, err := h.db.GetSubmissionByID(submissionID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "submission not found")
+		return nil, nil, false
+	}
+
+	if submission.UserID != user.ID {
+		respondError(w, http.StatusForbidden, "you can only change your own submissions")
+		return nil, nil, false
+	}
+
+	return user, submission, true
+}
@@ -0,0 +1,126 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+type contextKey string
+
+const (
+	loggerContextKey    contextKey = "logger"
+	requestIDContextKey contextKey = "request_id"
+)
+
+// maxRequestIDLength caps the length of client-supplied request IDs
+const maxRequestIDLength = 128
+
+// Config holds logger configuration
+type Config struct {
+	Format string `yaml:"format"` // "text" or "json"
+	Level  string `yaml:"level"`  // "debug", "info", "warn" or "error"
+}
+
+// DefaultConfig returns default logger configuration
+func DefaultConfig() Config {
+	return Config{
+		Format: "text",
+		Level:  "info",
+	}
+}
+
+// Validate checks that the format and level are recognized
+func (cfg Config) Validate() error {
+	switch strings.ToLower(cfg.Format) {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("invalid log format %q: must be 'text' or 'json'", cfg.Format)
+	}
+	_, err := ParseLevel(cfg.Level)
+	return err
+}
+
+// New creates a structured logger writing to w
+func New(cfg Config, w io.Writer) (*slog.Logger, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	level, _ := ParseLevel(cfg.Level)
+	opts := &slog.HandlerOptions{Level: level}
+
+	if strings.ToLower(cfg.Format) == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return slog.New(slog.NewTextHandler(w, opts)), nil
+}
+
+// ParseLevel converts a level name to a slog.Level
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q", level)
+	}
+}
+
+// WithLogger returns a context carrying the given logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey, logger)
+}
+
+// FromContext returns the request-scoped logger, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// WithRequestID returns a context carrying the given request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in the context
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey).(string)
+	return requestID
+}
+
+// NewRequestID generates a random 16-byte request ID
+func NewRequestID() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(bytes)
+}
+
+// ValidRequestID reports whether a client-supplied request ID is safe to reuse
+func ValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
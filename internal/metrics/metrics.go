@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/quentinsteinke/mkvmender/internal/database"
+	"github.com/quentinsteinke/mkvmender/internal/models"
+)
+
+const namespace = "mkvmender"
+
+// Metrics holds the Prometheus collectors exposed on /metrics.
+// A nil *Metrics is valid and records nothing.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	queryDuration   *prometheus.HistogramVec
+	queryErrors     *prometheus.CounterVec
+	lookups         *prometheus.CounterVec
+	uploads         prometheus.Counter
+	votes           *prometheus.CounterVec
+}
+
+// New creates the server metrics and registers them on a private registry.
+// If db is non-nil, its query durations and admin statistics are exported too.
+func New(db *database.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Total HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Database query latency by database.DB method.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"method"}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_query_errors_total",
+			Help:      "Database query errors by database.DB method.",
+		}, []string{"method"}),
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "lookups_total",
+			Help:      "Hash lookups by result (hit or miss).",
+		}, []string{"result"}),
+		uploads: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "uploads_total",
+			Help:      "Naming submissions uploaded.",
+		}),
+		votes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "votes_cast_total",
+			Help:      "Votes cast by type (up, down or delete).",
+		}, []string{"type"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.queryDuration,
+		m.queryErrors,
+		m.lookups,
+		m.uploads,
+		m.votes,
+	)
+
+	// Pre-initialize label values so ratios are defined from the first scrape
+	m.lookups.WithLabelValues("hit")
+	m.lookups.WithLabelValues("miss")
+
+	if db != nil {
+		db.SetQueryObserver(m.ObserveQuery)
+		m.registry.MustRegister(newStatsCollector(db))
+	}
+
+	return m
+}
+
+// Handler returns the HTTP handler serving metrics in Prometheus format
+func (m *Metrics) Handler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Registry returns the underlying registry
+func (m *Metrics) Registry() *prometheus.Registry {
+	if m == nil {
+		return nil
+	}
+	return m.registry
+}
+
+// ObserveRequest records a completed HTTP request
+func (m *Metrics) ObserveRequest(route, method string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	statusStr := strconv.Itoa(status)
+	m.requests.WithLabelValues(route, method, statusStr).Inc()
+	m.requestDuration.WithLabelValues(route, method, statusStr).Observe(duration.Seconds())
+}
+
+// ObserveQuery records a database query; it satisfies database.QueryObserver
+func (m *Metrics) ObserveQuery(method string, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.queryDuration.WithLabelValues(method).Observe(duration.Seconds())
+	if err != nil {
+		m.queryErrors.WithLabelValues(method).Inc()
+	}
+}
+
+// Lookup records a hash lookup and whether any submissions were found
+func (m *Metrics) Lookup(hit bool) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.lookups.WithLabelValues(result).Inc()
+}
+
+// Upload records a new naming submission
+func (m *Metrics) Upload() {
+	if m == nil {
+		return
+	}
+	m.uploads.Inc()
+}
+
+// Vote records a vote being cast
+func (m *Metrics) Vote(voteType models.VoteType) {
+	if m == nil {
+		return
+	}
+	label := "up"
+	if voteType == models.VoteDown {
+		label = "down"
+	}
+	m.votes.WithLabelValues(label).Inc()
+}
+
+// VoteDeleted records a vote being removed
+func (m *Metrics) VoteDeleted() {
+	if m == nil {
+		return
+	}
+	m.votes.WithLabelValues("delete").Inc()
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/quentinsteinke/mkvmender/internal/models"
+)
+
+func TestCounters(t *testing.T) {
+	m := New(nil)
+
+	m.Lookup(true)
+	m.Lookup(true)
+	m.Lookup(false)
+	m.Upload()
+	m.Vote(models.VoteUp)
+	m.Vote(models.VoteDown)
+	m.Vote(models.VoteUp)
+	m.VoteDeleted()
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"lookup hits", testutil.ToFloat64(m.lookups.WithLabelValues("hit")), 2},
+		{"lookup misses", testutil.ToFloat64(m.lookups.WithLabelValues("miss")), 1},
+		{"uploads", testutil.ToFloat64(m.uploads), 1},
+		{"upvotes", testutil.ToFloat64(m.votes.WithLabelValues("up")), 2},
+		{"downvotes", testutil.ToFloat64(m.votes.WithLabelValues("down")), 1},
+		{"vote deletions", testutil.ToFloat64(m.votes.WithLabelValues("delete")), 1},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestObserveQuery(t *testing.T) {
+	m := New(nil)
+
+	m.ObserveQuery("GetUserByID", 2*time.Millisecond, nil)
+	m.ObserveQuery("GetUserByID", 3*time.Millisecond, errors.New("boom"))
+	m.ObserveQuery("CreateVote", time.Millisecond, nil)
+
+	if n := testutil.CollectAndCount(m.queryDuration); n != 2 {
+		t.Errorf("query duration series = %d, want 2", n)
+	}
+	if got := testutil.ToFloat64(m.queryErrors.WithLabelValues("GetUserByID")); got != 1 {
+		t.Errorf("GetUserByID errors = %v, want 1", got)
+	}
+	if n := testutil.CollectAndCount(m.queryErrors); n != 1 {
+		t.Errorf("query error series = %d, want 1", n)
+	}
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+
+	m.ObserveRequest("/api/lookup", "GET", 200, time.Millisecond)
+	m.ObserveQuery("GetUserByID", time.Millisecond, nil)
+	m.Lookup(true)
+	m.Upload()
+	m.Vote(models.VoteUp)
+	m.VoteDeleted()
+
+	if m.Registry() != nil {
+		t.Error("nil metrics returned a registry")
+	}
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/quentinsteinke/mkvmender/internal/database"
+)
+
+// statsCollector exports the totals computed by GetAdminStats as gauges,
+// querying the database on every scrape
+type statsCollector struct {
+	db *database.DB
+
+	users          *prometheus.Desc
+	activeUsers    *prometheus.Desc
+	submissions    *prometheus.Desc
+	votes          *prometheus.Desc
+	pendingActions *prometheus.Desc
+	scrapeError    *prometheus.Desc
+}
+
+func newStatsCollector(db *database.DB) *statsCollector {
+	return &statsCollector{
+		db:             db,
+		users:          prometheus.NewDesc(namespace+"_users", "Registered users.", nil, nil),
+		activeUsers:    prometheus.NewDesc(namespace+"_users_active", "Users that are not suspended.", nil, nil),
+		submissions:    prometheus.NewDesc(namespace+"_submissions", "Stored naming submissions.", nil, nil),
+		votes:          prometheus.NewDesc(namespace+"_votes", "Stored votes.", nil, nil),
+		pendingActions: prometheus.NewDesc(namespace+"_pending_actions", "Moderation actions awaiting review.", nil, nil),
+		scrapeError:    prometheus.NewDesc(namespace+"_stats_scrape_error", "1 if the last statistics query failed.", nil, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.users
+	ch <- c.activeUsers
+	ch <- c.submissions
+	ch <- c.votes
+	ch <- c.pendingActions
+	ch <- c.scrapeError
+}
+
+// Collect implements prometheus.Collector
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.db.GetAdminStats()
+	if err != nil {
+		slog.Warn("failed to collect admin stats for metrics", "error", err)
+		ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, 1)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.users, prometheus.GaugeValue, float64(stats.TotalUsers))
+	ch <- prometheus.MustNewConstMetric(c.activeUsers, prometheus.GaugeValue, float64(stats.ActiveUsers))
+	ch <- prometheus.MustNewConstMetric(c.submissions, prometheus.GaugeValue, float64(stats.TotalSubmissions))
+	ch <- prometheus.MustNewConstMetric(c.votes, prometheus.GaugeValue, float64(stats.TotalVotes))
+	ch <- prometheus.MustNewConstMetric(c.pendingActions, prometheus.GaugeValue, float64(stats.PendingActions))
+	ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, 0)
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 // MediaType represents the type of media file
 type MediaType string
@@ -27,15 +31,317 @@ const (
 	RoleAdmin     UserRole = "admin"
 )
 
-// User represents a user in the system
+// Permission names an admin capability granted to staff roles
+type Permission string
+
+const (
+	PermViewSubmissions   Permission = "view_submissions"   // List and inspect submissions
+	PermDeleteSubmissions Permission = "delete_submissions" // Delete submissions
+	PermEditSubmissions   Permission = "edit_submissions"   // Roll back and merge submissions
+	PermViewUsers         Permission = "view_users"         // List and inspect users
+	PermSuspendUsers      Permission = "suspend_users"      // Suspend and reactivate users
+	PermChangeRoles       Permission = "change_roles"       // Promote and demote users
+	PermManageInvites     Permission = "manage_invites"     // Create, list and revoke invite codes
+	PermManageKeys        Permission = "manage_keys"        // List and revoke other users' API keys
+	PermViewStats         Permission = "view_stats"         // View dashboard statistics
+	PermReviewVotes       Permission = "review_votes"       // Review flagged voting and neutralize votes
+	PermViewAuditLog      Permission = "view_audit_log"     // Read the moderation log
+)
+
+// RolePermissions is the permission matrix: what each role may do on the
+// admin API. Roles not listed have no admin permissions.
+var RolePermissions = map[UserRole][]Permission{
+	RoleModerator: {
+		PermViewSubmissions,
+		PermDeleteSubmissions,
+		PermEditSubmissions,
+		PermViewUsers,
+		PermSuspendUsers,
+		PermViewStats,
+		PermReviewVotes,
+		PermViewAuditLog,
+	},
+	RoleAdmin: {
+		PermViewSubmissions,
+		PermDeleteSubmissions,
+		PermEditSubmissions,
+		PermViewUsers,
+		PermSuspendUsers,
+		PermChangeRoles,
+		PermManageInvites,
+		PermManageKeys,
+		PermViewStats,
+		PermReviewVotes,
+		PermViewAuditLog,
+	},
+}
+
+// Can reports whether the role has permission p
+func (r UserRole) Can(p Permission) bool {
+	for _, perm := range RolePermissions[r] {
+		if perm == p {
+			return true
+		}
+	}
+	return false
+}
+
+// CanModerate reports whether the role may act on an account with the
+// target role: admins on anyone, moderators only on regular users
+func (r UserRole) CanModerate(target UserRole) bool {
+	switch r {
+	case RoleAdmin:
+		return true
+	case RoleModerator:
+		return target == RoleUser
+	}
+	return false
+}
+
+// IsStaff reports whether the role has access to the admin API
+func (r UserRole) IsStaff() bool {
+	return len(RolePermissions[r]) > 0
+}
+
+// TrustLevel is earned automatically from reputation and unlocks
+// privileges and heavier votes
+type TrustLevel int
+
+const (
+	TrustNew     TrustLevel = iota // Recently joined or little reputation
+	TrustMember                    // Some reputation
+	TrustTrusted                   // A solid record of good names and votes
+	TrustVeteran                   // A long record of good names and votes
+)
+
+// trustLevelNames are the names used for trust levels in the API
+var trustLevelNames = []string{"new", "member", "trusted", "veteran"}
+
+// TrustThresholds is the reputation needed for each trust level
+var TrustThresholds = map[TrustLevel]int{
+	TrustNew:     0,
+	TrustMember:  10,
+	TrustTrusted: 100,
+	TrustVeteran: 500,
+}
+
+// TrustVoteWeights is how much a vote counts toward ranking at each trust
+// level
+var TrustVoteWeights = map[TrustLevel]float64{
+	TrustNew:     0.5,
+	TrustMember:  1,
+	TrustTrusted: 1.5,
+	TrustVeteran: 2,
+}
+
+// TrustLevelFor returns the trust level earned by reputation
+func TrustLevelFor(reputation int) TrustLevel {
+	level := TrustNew
+	for l, threshold := range TrustThresholds {
+		if reputation >= threshold && l > level {
+			level = l
+		}
+	}
+	return level
+}
+
+// VoteWeight returns how much a vote at trust level t counts
+func (t TrustLevel) VoteWeight() float64 {
+	if weight, ok := TrustVoteWeights[t]; ok {
+		return weight
+	}
+	return 1
+}
+
+// String returns the API name of the trust level
+func (t TrustLevel) String() string {
+	if t >= 0 && int(t) < len(trustLevelNames) {
+		return trustLevelNames[t]
+	}
+	return fmt.Sprintf("level_%d", int(t))
+}
+
+// MarshalText encodes the trust level by name
+func (t TrustLevel) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes a trust level name
+func (t *TrustLevel) UnmarshalText(text []byte) error {
+	for i, name := range trustLevelNames {
+		if name == string(text) {
+			*t = TrustLevel(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown trust level %q", text)
+}
+
+// User represents a user in the system. APIKey is only set in the
+// registration response; keys are otherwise never returned.
 type User struct {
-	ID        int64     `json:"id"`
-	Username  string    `json:"username"`
-	APIKey    string    `json:"api_key,omitempty"`
-	Role      UserRole  `json:"role"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID       int64    `json:"id"`
+	Username string   `json:"username"`
+	APIKey   string   `json:"api_key,omitempty"`
+	Role     UserRole `json:"role"`
+	IsActive bool     `json:"is_active"`
+	// SuspendedUntil is set for temporary suspensions; the account is
+	// reactivated automatically once it passes
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	Reputation     int        `json:"reputation"`
+	TrustLevel     TrustLevel `json:"trust_level"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// RegistrationGate is an extra check a server may require to register
+type RegistrationGate string
+
+const (
+	GateNone        RegistrationGate = "none"          // Anyone may register
+	GateInvite      RegistrationGate = "invite"        // An invite code is required
+	GateProofOfWork RegistrationGate = "proof_of_work" // A solved proof-of-work challenge is required
+)
+
+// AccountDeletionPolicy controls what happens to a user's submissions when
+// they delete their account
+type AccountDeletionPolicy string
+
+const (
+	DeletionReassign AccountDeletionPolicy = "reassign" // Keep submissions, credited to the shared "deleted" user
+	DeletionDelete   AccountDeletionPolicy = "delete"   // Delete submissions along with the account
+)
+
+// DeleteAccountRequest confirms an account deletion; Confirm must be the
+// account's username
+type DeleteAccountRequest struct {
+	Confirm string `json:"confirm"`
+}
+
+// DeleteAccountResponse reports what happened to a deleted account's
+// submissions
+type DeleteAccountResponse struct {
+	Policy      AccountDeletionPolicy `json:"policy"`
+	Submissions int                   `json:"submissions"`
+}
+
+// ExportedSubmission is one of a user's submissions in their data export,
+// including any that are deleted but not yet purged
+type ExportedSubmission struct {
+	NamingSubmission
+	Hash      string          `json:"hash"`
+	Hidden    bool            `json:"hidden"`
+	DeletedAt *time.Time      `json:"deleted_at,omitempty"`
+	Metadata  *NamingMetadata `json:"metadata,omitempty"`
+}
+
+// AccountExport is everything the service holds about a user
+type AccountExport struct {
+	ExportedAt  time.Time            `json:"exported_at"`
+	User        User                 `json:"user"`
+	APIKeys     []APIKey             `json:"api_keys"`
+	Submissions []ExportedSubmission `json:"submissions"`
+	Votes       []Vote               `json:"votes"`
+	Reports     []SubmissionReport   `json:"reports"`
+}
+
+// RegisterRequest represents a registration request. InviteCode is needed
+// when the server requires invites; Challenge and Nonce carry a solved
+// proof-of-work challenge when the server requires one.
+type RegisterRequest struct {
+	Username   string `json:"username"`
+	InviteCode string `json:"invite_code,omitempty"`
+	Challenge  string `json:"challenge,omitempty"`
+	Nonce      string `json:"nonce,omitempty"`
+}
+
+// RegistrationChallenge is a proof-of-work challenge: find a Nonce such
+// that SHA-256(Challenge + ":" + Nonce) starts with Difficulty zero bits
+type RegistrationChallenge struct {
+	Challenge  string    `json:"challenge"`
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Invite represents an invite code for servers that gate registration
+type Invite struct {
+	ID        int64      `json:"id"`
+	Code      string     `json:"code"`
+	CreatedBy int64      `json:"created_by"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// CreateInviteRequest represents a request to create an invite code.
+// MaxUses defaults to 1; the code never expires unless Duration (e.g.
+// "72h" or "7d") is given.
+type CreateInviteRequest struct {
+	MaxUses  int    `json:"max_uses,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// Scope limits what an API key may be used for
+type Scope string
+
+const (
+	ScopeRead   Scope = "read"   // Read account data (verify, key list, own votes)
+	ScopeUpload Scope = "upload" // Upload naming submissions
+	ScopeVote   Scope = "vote"   // Cast and remove votes
+	ScopeAdmin  Scope = "admin"  // Use admin endpoints (admin/moderator accounts only)
+)
+
+// AllScopes lists every scope in display order
+var AllScopes = []Scope{ScopeRead, ScopeUpload, ScopeVote, ScopeAdmin}
+
+// DefaultScopes are granted to keys created without explicit scopes
+var DefaultScopes = []Scope{ScopeRead, ScopeUpload, ScopeVote}
+
+// ValidScope reports whether s is a known scope
+func ValidScope(s Scope) bool {
+	for _, scope := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey represents a named API key belonging to a user. Only a hash of the
+// secret is stored; Key is set once, when the key is created or rotated.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []Scope    `json:"scopes"`
+	Key        string     `json:"key,omitempty"`
+	Current    bool       `json:"current,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// HasScope reports whether the key grants scope
+func (k *APIKey) HasScope(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPIKeyRequest represents a request to create a named API key.
+// Scopes defaults to DefaultScopes when empty.
+type CreateAPIKeyRequest struct {
+	Name   string  `json:"name"`
+	Scopes []Scope `json:"scopes,omitempty"`
+}
+
+// APIKeyListResponse represents the caller's, or for admins any user's, API keys
+type APIKeyListResponse struct {
+	Keys []APIKey `json:"keys"`
 }
 
 // FileHash represents a hashed media file
@@ -49,10 +355,13 @@ type FileHash struct {
 
 // NamingSubmission represents a user's submission for a file name
 type NamingSubmission struct {
-	ID        int64     `json:"id"`
-	HashID    int64     `json:"hash_id"`
-	UserID    int64     `json:"user_id"`
-	Filename  string    `json:"filename"`
+	ID       int64  `json:"id"`
+	HashID   int64  `json:"hash_id"`
+	UserID   int64  `json:"user_id"`
+	Filename string `json:"filename"`
+	// Pending submissions await a moderator's approval before they appear
+	// in lookups and searches
+	Pending   bool      `json:"pending"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -82,19 +391,37 @@ type NamingMetadata struct {
 
 // SubmissionWithVotes represents a naming submission with vote counts
 type SubmissionWithVotes struct {
-	ID         int64     `json:"id"`
-	HashID     int64     `json:"hash_id"`
-	UserID     int64     `json:"user_id"`
-	Filename   string    `json:"filename"`
-	CreatedAt  time.Time `json:"created_at"`
-	Hash       string    `json:"hash"`
-	FileSize   int64     `json:"file_size"`
-	MediaType  MediaType `json:"media_type"`
-	Username   string    `json:"username"`
-	VoteScore  int       `json:"vote_score"`
-	Upvotes    int       `json:"upvotes"`
-	Downvotes  int       `json:"downvotes"`
-	Metadata   *NamingMetadata `json:"metadata,omitempty"`
+	ID        int64     `json:"id"`
+	HashID    int64     `json:"hash_id"`
+	UserID    int64     `json:"user_id"`
+	Filename  string    `json:"filename"`
+	CreatedAt time.Time `json:"created_at"`
+	// Hidden submissions are left out of lookups and searches until a
+	// moderator resolves the reports against them
+	Hidden bool `json:"hidden"`
+	// Pending submissions await a moderator's approval
+	Pending   bool      `json:"pending"`
+	Hash      string    `json:"hash"`
+	FileSize  int64     `json:"file_size"`
+	MediaType MediaType `json:"media_type"`
+	Username  string    `json:"username"`
+	// UserReputation and UserTrustLevel describe the uploader
+	UserReputation int        `json:"user_reputation"`
+	UserTrustLevel TrustLevel `json:"user_trust_level"`
+	VoteScore      int        `json:"vote_score"`
+	Upvotes        int        `json:"upvotes"`
+	Downvotes      int        `json:"downvotes"`
+	// WeightedUpvotes and WeightedDownvotes count each vote by the voter's
+	// trust level (see TrustVoteWeights); ranking uses these
+	WeightedUpvotes   float64 `json:"weighted_upvotes"`
+	WeightedDownvotes float64 `json:"weighted_downvotes"`
+	// Confidence in [0, 1] that this is the right name, from the ranking
+	// used for the response
+	Confidence float64 `json:"confidence"`
+	// MyVote is the caller's vote on the submission, 0 if they have not
+	// voted; only set on authenticated lookups
+	MyVote   *VoteType       `json:"my_vote,omitempty"`
+	Metadata *NamingMetadata `json:"metadata,omitempty"`
 }
 
 // HashLookupRequest represents a request to lookup naming submissions by hash
@@ -105,19 +432,65 @@ type HashLookupRequest struct {
 
 // HashLookupResponse represents the response with available naming options
 type HashLookupResponse struct {
-	Hash        string                 `json:"hash"`
-	FileSize    int64                  `json:"file_size"`
-	MediaType   MediaType              `json:"media_type"`
-	Submissions []SubmissionWithVotes  `json:"submissions"`
+	Hash        string                `json:"hash"`
+	FileSize    int64                 `json:"file_size"`
+	MediaType   MediaType             `json:"media_type"`
+	Ranking     string                `json:"ranking"`
+	Submissions []SubmissionWithVotes `json:"submissions"`
 }
 
 // UploadRequest represents a request to upload a new naming submission
 type UploadRequest struct {
-	Hash      string             `json:"hash"`
-	FileSize  int64              `json:"file_size"`
-	MediaType MediaType          `json:"media_type"`
-	Filename  string             `json:"filename"`
-	Metadata  *NamingMetadata    `json:"metadata,omitempty"`
+	Hash      string          `json:"hash"`
+	FileSize  int64           `json:"file_size"`
+	MediaType MediaType       `json:"media_type"`
+	Filename  string          `json:"filename"`
+	Metadata  *NamingMetadata `json:"metadata,omitempty"`
+}
+
+// UploadResponse represents the result of an upload. When the hash already
+// has a submission with the same name, no new submission is created: the
+// upload counts as an upvote for the existing one, which is returned with
+// DuplicateOf set to its ID.
+type UploadResponse struct {
+	NamingSubmission
+	DuplicateOf *int64 `json:"duplicate_of,omitempty"`
+}
+
+// UpdateSubmissionRequest represents an owner's edit of a submission. An
+// empty Filename keeps the current name, and metadata fields left nil keep
+// their current values.
+type UpdateSubmissionRequest struct {
+	Filename string          `json:"filename,omitempty"`
+	Metadata *NamingMetadata `json:"metadata,omitempty"`
+	Reason   *string         `json:"reason,omitempty"`
+}
+
+// UpdateSubmissionResponse represents an edited submission. VotesReset is
+// set when the filename changed enough that earlier votes were cleared.
+type UpdateSubmissionResponse struct {
+	Submission SubmissionWithVotes `json:"submission"`
+	VotesReset bool                `json:"votes_reset"`
+}
+
+// SubmissionRevision is a snapshot of a submission's filename and metadata
+// after a change, with who made it and why. The first revision is the
+// original upload.
+type SubmissionRevision struct {
+	ID           int64     `json:"id"`
+	SubmissionID int64     `json:"submission_id"`
+	UserID       int64     `json:"user_id"`
+	Username     string    `json:"username"`
+	Filename     string    `json:"filename"`
+	Title        *string   `json:"title,omitempty"`
+	Year         *int      `json:"year,omitempty"`
+	Season       *int      `json:"season,omitempty"`
+	Episode      *int      `json:"episode,omitempty"`
+	Quality      *string   `json:"quality,omitempty"`
+	Source       *string   `json:"source,omitempty"`
+	Reason       *string   `json:"reason,omitempty"`
+	VotesReset   bool      `json:"votes_reset"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 // VoteRequest represents a request to vote on a submission
@@ -126,12 +499,25 @@ type VoteRequest struct {
 	VoteType     VoteType `json:"vote_type"`
 }
 
-// ErrorResponse represents an API error response
+// ErrorResponse represents an API error response. Code is a stable,
+// machine-readable identifier for errors clients may want to handle.
 type ErrorResponse struct {
 	Error   string `json:"error"`
+	Code    string `json:"code,omitempty"`
 	Message string `json:"message,omitempty"`
 }
 
+// Error codes returned in ErrorResponse.Code
+const (
+	ErrorCodeAccountSuspended    = "account_suspended"
+	ErrorCodeInvalidUsername     = "invalid_username"
+	ErrorCodeUsernameTaken       = "username_taken"
+	ErrorCodeInviteRequired      = "invite_required"
+	ErrorCodeInvalidInvite       = "invalid_invite"
+	ErrorCodeProofOfWorkRequired = "proof_of_work_required"
+	ErrorCodeInsufficientTrust   = "insufficient_trust"
+)
+
 // SuccessResponse represents a generic success response
 type SuccessResponse struct {
 	Success bool   `json:"success"`
@@ -158,18 +544,64 @@ type SearchResult struct {
 // SearchResponse represents search results
 type SearchResponse struct {
 	Query   string         `json:"query"`
+	Ranking string         `json:"ranking"`
 	Results []SearchResult `json:"results"`
 }
 
+// Moderation action types recorded in ModerationAction.ActionType
+const (
+	ActionDeleteSubmission   = "delete_submission"
+	ActionChangeRole         = "change_role"
+	ActionSuspendUser        = "suspend_user"
+	ActionActivateUser       = "activate_user"
+	ActionCreateInvite       = "create_invite"
+	ActionRevokeInvite       = "revoke_invite"
+	ActionRollbackSubmission = "rollback_submission"
+	ActionMergeSubmission    = "merge_submission"
+	ActionConfirmVoteFlag    = "confirm_vote_flag"
+	ActionDismissReports     = "dismiss_reports"
+	ActionApproveSubmission  = "approve_submission"
+	ActionRejectSubmission   = "reject_submission"
+	ActionEditSubmission     = "edit_submission"
+	ActionDismissVoteFlag    = "dismiss_vote_flag"
+	ActionRestoreSubmission  = "restore_submission"
+	ActionBulkDelete         = "bulk_delete_submissions"
+	ActionSuspendPurgeVotes  = "suspend_purge_votes"
+	ActionRevokeAPIKey       = "revoke_api_key"
+)
+
+// Moderation target types recorded in ModerationAction.TargetType
+const (
+	TargetUser       = "user"
+	TargetSubmission = "submission"
+	TargetInvite     = "invite"
+	TargetVoteFlag   = "vote_flag"
+	// TargetSubmissions is a group of submissions chosen by filename or
+	// hash, listed in the action's details
+	TargetSubmissions = "submissions"
+)
+
 // ModerationAction represents an admin action
 type ModerationAction struct {
-	ID         int64     `json:"id"`
-	AdminID    int64     `json:"admin_id"`
-	ActionType string    `json:"action_type"`
-	TargetType string    `json:"target_type"`
-	TargetID   int64     `json:"target_id"`
-	Reason     *string   `json:"reason,omitempty"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID         int64   `json:"id"`
+	AdminID    int64   `json:"admin_id"`
+	ActionType string  `json:"action_type"`
+	TargetType string  `json:"target_type"`
+	TargetID   int64   `json:"target_id"`
+	Reason     *string `json:"reason,omitempty"`
+	// ExpiresAt records when a temporary action such as a suspension ends
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Details describes what a bulk action matched and changed, or which
+	// API key was revoked
+	Details   json.RawMessage `json:"details,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ModerationLogEntry is a logged moderation action with the name of the
+// staff member who took it
+type ModerationLogEntry struct {
+	ModerationAction
+	AdminUsername string `json:"admin_username"`
 }
 
 // AdminStats represents system statistics for admin dashboard
@@ -184,17 +616,20 @@ type AdminStats struct {
 // AdminSubmissionListItem represents a submission in the admin list
 type AdminSubmissionListItem struct {
 	SubmissionWithVotes
-	UserRole UserRole `json:"user_role"`
+	UserRole  UserRole   `json:"user_role"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy *int64     `json:"deleted_by,omitempty"`
 }
 
 // AdminUserListItem represents a user in the admin list
 type AdminUserListItem struct {
-	ID               int64     `json:"id"`
-	Username         string    `json:"username"`
-	Role             UserRole  `json:"role"`
-	IsActive         bool      `json:"is_active"`
-	SubmissionCount  int       `json:"submission_count"`
-	CreatedAt        time.Time `json:"created_at"`
+	ID              int64      `json:"id"`
+	Username        string     `json:"username"`
+	Role            UserRole   `json:"role"`
+	IsActive        bool       `json:"is_active"`
+	SuspendedUntil  *time.Time `json:"suspended_until,omitempty"`
+	SubmissionCount int        `json:"submission_count"`
+	CreatedAt       time.Time  `json:"created_at"`
 }
 
 // ChangeRoleRequest represents a request to change user role
@@ -202,13 +637,184 @@ type ChangeRoleRequest struct {
 	Role UserRole `json:"role"`
 }
 
-// ChangeStatusRequest represents a request to change user active status
+// ChangeStatusRequest represents a request to change user active status.
+// A suspension is permanent unless SuspendedUntil (RFC 3339) or Duration
+// (e.g. "72h" or "7d") is given.
 type ChangeStatusRequest struct {
-	IsActive bool    `json:"is_active"`
-	Reason   *string `json:"reason,omitempty"`
+	IsActive       bool       `json:"is_active"`
+	Reason         *string    `json:"reason,omitempty"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	Duration       string     `json:"duration,omitempty"`
 }
 
 // DeleteSubmissionRequest represents a request to delete a submission
 type DeleteSubmissionRequest struct {
 	Reason *string `json:"reason,omitempty"`
 }
+
+// BulkDeleteRequest represents a bulk deletion of submissions. Pattern is
+// a filename with * and ? wildcards, matched ignoring case; when both
+// Pattern and Hashes are given a submission must match both. DryRun reports
+// what would be deleted without deleting it.
+type BulkDeleteRequest struct {
+	Pattern string   `json:"pattern,omitempty"`
+	Hashes  []string `json:"hashes,omitempty"`
+	DryRun  bool     `json:"dry_run"`
+	Reason  *string  `json:"reason,omitempty"`
+}
+
+// BulkSuspendRequest represents suspending a user and deleting all their
+// votes. The suspension is permanent unless SuspendedUntil or Duration is
+// given, as in ChangeStatusRequest.
+type BulkSuspendRequest struct {
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	Duration       string     `json:"duration,omitempty"`
+	DryRun         bool       `json:"dry_run"`
+	Reason         *string    `json:"reason,omitempty"`
+}
+
+// BulkModerationResponse reports what a bulk operation changed, or would
+// change on a dry run. ActionID is the moderation log entry recording it.
+type BulkModerationResponse struct {
+	DryRun        bool    `json:"dry_run"`
+	Submissions   int     `json:"submissions"`
+	SubmissionIDs []int64 `json:"submission_ids"`
+	Votes         int     `json:"votes"`
+	ActionID      int64   `json:"action_id,omitempty"`
+}
+
+// DuplicateGroup lists submissions for one file whose names differ only in
+// case, spacing and punctuation
+type DuplicateGroup struct {
+	Hash        string                `json:"hash"`
+	Submissions []SubmissionWithVotes `json:"submissions"`
+}
+
+// MergeSubmissionsRequest represents a request to merge duplicate
+// submissions into the one named in the URL
+type MergeSubmissionsRequest struct {
+	SourceIDs []int64 `json:"source_ids"`
+	Reason    *string `json:"reason,omitempty"`
+}
+
+// RollbackSubmissionRequest represents a request to restore a submission to
+// one of its earlier revisions
+type RollbackSubmissionRequest struct {
+	RevisionID int64   `json:"revision_id"`
+	Reason     *string `json:"reason,omitempty"`
+}
+
+// Vote manipulation patterns recorded in VoteFlag.Pattern
+const (
+	// FlagSingleTarget: every vote the account cast went to one submitter
+	FlagSingleTarget = "single_target"
+	// FlagNewAccountBurst: one of several new accounts that upvoted the
+	// same submission within a short time
+	FlagNewAccountBurst = "new_account_burst"
+	// FlagVotingRing: two accounts that repeatedly upvote each other
+	FlagVotingRing = "voting_ring"
+)
+
+// Vote flag review states recorded in VoteFlag.Status
+const (
+	FlagOpen      = "open"
+	FlagConfirmed = "confirmed"
+	FlagDismissed = "dismissed"
+)
+
+// VoteFlag is an entry in the vote manipulation review queue: a voter whose
+// upvotes for the target user's submissions match a suspicious pattern.
+// While Neutralized, those votes are left out of scores and reputation.
+type VoteFlag struct {
+	ID             int64      `json:"id"`
+	VoterID        int64      `json:"voter_id"`
+	VoterUsername  string     `json:"voter_username"`
+	TargetUserID   int64      `json:"target_user_id"`
+	TargetUsername string     `json:"target_username"`
+	Pattern        string     `json:"pattern"`
+	Details        string     `json:"details"`
+	VoteCount      int        `json:"vote_count"`
+	Status         string     `json:"status"`
+	Neutralized    bool       `json:"neutralized"`
+	ReviewedBy     *int64     `json:"reviewed_by,omitempty"`
+	ReviewedAt     *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// ReviewVoteFlagRequest represents a moderator's decision on a vote flag:
+// "confirm" neutralizes the votes, "dismiss" restores them
+type ReviewVoteFlagRequest struct {
+	Action string  `json:"action"`
+	Reason *string `json:"reason,omitempty"`
+}
+
+// ReviewSubmissionRequest represents a moderator approving, rejecting or
+// restoring a submission
+type ReviewSubmissionRequest struct {
+	Reason *string `json:"reason,omitempty"`
+}
+
+// ReportReason is why a user reported a submission
+type ReportReason string
+
+const (
+	ReportWrongTitle   ReportReason = "wrong_title"
+	ReportWrongEpisode ReportReason = "wrong_episode"
+	ReportSpam         ReportReason = "spam"
+	ReportOffensive    ReportReason = "offensive"
+)
+
+// ValidReportReason reports whether r is a known report reason
+func ValidReportReason(r ReportReason) bool {
+	switch r {
+	case ReportWrongTitle, ReportWrongEpisode, ReportSpam, ReportOffensive:
+		return true
+	}
+	return false
+}
+
+// Report states recorded in SubmissionReport.Status
+const (
+	ReportOpen      = "open"
+	ReportResolved  = "resolved"
+	ReportDismissed = "dismissed"
+)
+
+// SubmissionReport is a user's report of a bad submission, queued for
+// moderators
+type SubmissionReport struct {
+	ID               int64        `json:"id"`
+	SubmissionID     int64        `json:"submission_id"`
+	Filename         string       `json:"filename"`
+	ReporterID       int64        `json:"reporter_id"`
+	ReporterUsername string       `json:"reporter_username"`
+	Reason           ReportReason `json:"reason"`
+	Details          *string      `json:"details,omitempty"`
+	Status           string       `json:"status"`
+	ResolvedBy       *int64       `json:"resolved_by,omitempty"`
+	ResolvedAt       *time.Time   `json:"resolved_at,omitempty"`
+	CreatedAt        time.Time    `json:"created_at"`
+}
+
+// CreateReportRequest represents a request to report a submission
+type CreateReportRequest struct {
+	Reason  ReportReason `json:"reason"`
+	Details *string      `json:"details,omitempty"`
+}
+
+// CreateReportResponse represents a filed report. Hidden is set when the
+// report pushed the submission over the auto-hide threshold.
+type CreateReportResponse struct {
+	Report SubmissionReport `json:"report"`
+	Hidden bool             `json:"hidden"`
+}
+
+// ResolveReportRequest represents a moderator's resolution of the open
+// reports on a submission: "dismiss" them, "delete" the submission or
+// "edit" it with Filename and/or Metadata
+type ResolveReportRequest struct {
+	Action   string          `json:"action"`
+	Filename string          `json:"filename,omitempty"`
+	Metadata *NamingMetadata `json:"metadata,omitempty"`
+	Reason   *string         `json:"reason,omitempty"`
+}
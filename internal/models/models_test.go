@@ -0,0 +1,88 @@
+package models
+
+import "testing"
+
+func TestRolePermissions(t *testing.T) {
+	tests := []struct {
+		perm      Permission
+		moderator bool
+		admin     bool
+	}{
+		{PermViewSubmissions, true, true},
+		{PermDeleteSubmissions, true, true},
+		{PermEditSubmissions, true, true},
+		{PermViewUsers, true, true},
+		{PermSuspendUsers, true, true},
+		{PermChangeRoles, false, true},
+		{PermManageInvites, false, true},
+		{PermManageKeys, false, true},
+		{PermViewStats, true, true},
+		{PermReviewVotes, true, true},
+		{PermViewAuditLog, true, true},
+	}
+
+	for _, tt := range tests {
+		if RoleUser.Can(tt.perm) {
+			t.Errorf("user can %s", tt.perm)
+		}
+		if got := RoleModerator.Can(tt.perm); got != tt.moderator {
+			t.Errorf("moderator can %s = %v, want %v", tt.perm, got, tt.moderator)
+		}
+		if got := RoleAdmin.Can(tt.perm); got != tt.admin {
+			t.Errorf("admin can %s = %v, want %v", tt.perm, got, tt.admin)
+		}
+	}
+
+	// Every permission granted to a role must appear in the table above
+	covered := make(map[Permission]bool, len(tests))
+	for _, tt := range tests {
+		covered[tt.perm] = true
+	}
+	for role, perms := range RolePermissions {
+		for _, perm := range perms {
+			if !covered[perm] {
+				t.Errorf("%s has %s, which is not covered by this test", role, perm)
+			}
+		}
+	}
+}
+
+func TestIsStaff(t *testing.T) {
+	tests := []struct {
+		role UserRole
+		want bool
+	}{
+		{RoleUser, false},
+		{RoleModerator, true},
+		{RoleAdmin, true},
+		{UserRole("owner"), false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.role.IsStaff(); got != tt.want {
+			t.Errorf("%s.IsStaff() = %v, want %v", tt.role, got, tt.want)
+		}
+	}
+}
+
+func TestCanModerate(t *testing.T) {
+	tests := []struct {
+		role   UserRole
+		target UserRole
+		want   bool
+	}{
+		{RoleUser, RoleUser, false},
+		{RoleModerator, RoleUser, true},
+		{RoleModerator, RoleModerator, false},
+		{RoleModerator, RoleAdmin, false},
+		{RoleAdmin, RoleUser, true},
+		{RoleAdmin, RoleModerator, true},
+		{RoleAdmin, RoleAdmin, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.role.CanModerate(tt.target); got != tt.want {
+			t.Errorf("%s.CanModerate(%s) = %v, want %v", tt.role, tt.target, got, tt.want)
+		}
+	}
+}
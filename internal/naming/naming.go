@@ -0,0 +1,40 @@
+// Package naming compares submitted filenames.
+package naming
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// ErrUnsafeFilename is returned for filenames that are not a single path
+// element, which clients would turn into a rename out of the file's
+// directory
+var ErrUnsafeFilename = errors.New("filename must not contain path separators or be . or ..")
+
+// CheckFilename returns ErrUnsafeFilename unless name is a single path
+// element on every platform
+func CheckFilename(name string) error {
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return ErrUnsafeFilename
+	}
+	return nil
+}
+
+// Key returns the form of a filename used to compare names: its letters and
+// digits, lowercased. Names with the same key differ only in case, spacing
+// and punctuation, e.g. "The.Matrix.1999.mkv" and "The Matrix (1999).mkv".
+func Key(name string) string {
+	var key strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			key.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return key.String()
+}
+
+// Same reports whether two filenames have the same Key
+func Same(a, b string) bool {
+	return Key(a) == Key(b)
+}
@@ -0,0 +1,26 @@
+package naming
+
+import "testing"
+
+func TestCheckFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"The Matrix (1999).mkv", true},
+		{"Movie... (2000).mkv", true},
+		{"..mkv", true},
+		{"../../x.mkv", false},
+		{"sub/dir/x.mkv", false},
+		{`..\x.mkv`, false},
+		{"/etc/passwd", false},
+		{".", false},
+		{"..", false},
+	}
+
+	for _, tt := range tests {
+		if err := CheckFilename(tt.name); (err == nil) != tt.ok {
+			t.Errorf("CheckFilename(%q) = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
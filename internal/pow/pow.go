@@ -0,0 +1,152 @@
+// Package pow implements the hashcash-style proof-of-work challenge that can
+// gate registration. The server issues signed, expiring challenges and the
+// client searches for a nonce whose SHA-256 hash, together with the
+// challenge, starts with a given number of zero bits.
+package pow
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Issuer creates and verifies challenges. Challenges are stateless apart
+// from a record of solved ones, which prevents a solution being reused.
+type Issuer struct {
+	secret     []byte
+	difficulty int
+	ttl        time.Duration
+
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+// NewIssuer creates an Issuer requiring difficulty leading zero bits, with
+// challenges valid for ttl. Challenges are signed with a random per-process
+// secret, so outstanding ones become invalid on restart.
+func NewIssuer(difficulty int, ttl time.Duration) (*Issuer, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate challenge secret: %w", err)
+	}
+
+	return &Issuer{
+		secret:     secret,
+		difficulty: difficulty,
+		ttl:        ttl,
+		used:       make(map[string]time.Time),
+	}, nil
+}
+
+// Difficulty returns the number of leading zero bits a solution needs
+func (i *Issuer) Difficulty() int {
+	return i.difficulty
+}
+
+// Issue returns a new challenge and when it expires
+func (i *Issuer) Issue() (string, time.Time, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+
+	expires := time.Now().Add(i.ttl).Truncate(time.Second)
+	payload := fmt.Sprintf("%d.%d.%s", expires.Unix(), i.difficulty, hex.EncodeToString(random))
+	return payload + "." + i.sign(payload), expires, nil
+}
+
+// Verify checks that nonce solves challenge and that the challenge was
+// issued by i, has not expired and has not been used before. A successful
+// verification consumes the challenge.
+func (i *Issuer) Verify(challenge, nonce string) error {
+	payload, signature, ok := cutLast(challenge, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(i.sign(payload))) {
+		return fmt.Errorf("invalid challenge")
+	}
+
+	parts := strings.SplitN(payload, ".", 3)
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid challenge")
+	}
+	expiresUnix, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid challenge")
+	}
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return fmt.Errorf("invalid challenge")
+	}
+
+	expires := time.Unix(expiresUnix, 0)
+	now := time.Now()
+	if now.After(expires) {
+		return fmt.Errorf("challenge expired")
+	}
+	if !Valid(challenge, nonce, difficulty) {
+		return fmt.Errorf("incorrect proof of work")
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for c, exp := range i.used {
+		if now.After(exp) {
+			delete(i.used, c)
+		}
+	}
+	if _, seen := i.used[challenge]; seen {
+		return fmt.Errorf("challenge already used")
+	}
+	i.used[challenge] = expires
+
+	return nil
+}
+
+// sign returns the hex HMAC of payload
+func (i *Issuer) sign(payload string) string {
+	mac := hmac.New(sha256.New, i.secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Solve finds a nonce for challenge with the given difficulty. It takes
+// about 2^difficulty hashes.
+func Solve(challenge string, difficulty int) string {
+	for n := uint64(0); ; n++ {
+		nonce := strconv.FormatUint(n, 36)
+		if Valid(challenge, nonce, difficulty) {
+			return nonce
+		}
+	}
+}
+
+// Valid reports whether SHA-256(challenge ":" nonce) starts with at least
+// difficulty zero bits
+func Valid(challenge, nonce string, difficulty int) bool {
+	sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			zeros += bits.LeadingZeros8(b)
+			break
+		}
+		zeros += 8
+	}
+
+	return zeros >= difficulty
+}
+
+// cutLast slices s around the last instance of sep
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
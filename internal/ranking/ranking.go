@@ -0,0 +1,132 @@
+// Package ranking orders naming submissions by how confident we can be that
+// they are right, rather than by raw vote score, so that a name with a
+// couple of upvotes does not outrank one with a long, mostly positive
+// record.
+package ranking
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quentinsteinke/mkvmender/internal/models"
+)
+
+// Method names a ranking method
+type Method string
+
+const (
+	// Wilson ranks by the lower bound of the Wilson score interval for the
+	// share of upvotes: the share we can be 95% sure of given the votes so
+	// far
+	Wilson Method = "wilson"
+	// Bayesian ranks by the share of upvotes after adding a few neutral
+	// prior votes, pulling submissions with little evidence toward 50%
+	Bayesian Method = "bayesian"
+	// Decay ranks by the Wilson lower bound, halved for every HalfLife of
+	// a submission's age, favouring recent names
+	Decay Method = "decay"
+)
+
+// Default is the ranking used when none is requested or configured
+const Default = Wilson
+
+const (
+	// wilsonZ is the normal quantile for a 95% confidence interval
+	wilsonZ = 1.96
+	// priorVotes is how many neutral votes Bayesian adds, half of them up
+	priorVotes = 4.0
+	// HalfLife is the age at which Decay halves a submission's confidence
+	HalfLife = 365 * 24 * time.Hour
+)
+
+// Func returns a confidence in [0, 1] from a submission's votes, weighted
+// by the voters' trust levels, and its age
+type Func func(upvotes, downvotes float64, age time.Duration) float64
+
+// methods holds the available ranking methods
+var methods = map[Method]Func{
+	Wilson: func(up, down float64, _ time.Duration) float64 {
+		return wilsonLowerBound(up, down)
+	},
+	Bayesian: func(up, down float64, _ time.Duration) float64 {
+		return (up + priorVotes/2) / (up + down + priorVotes)
+	},
+	Decay: func(up, down float64, age time.Duration) float64 {
+		if age < 0 {
+			age = 0
+		}
+		return wilsonLowerBound(up, down) * math.Exp2(-float64(age)/float64(HalfLife))
+	},
+}
+
+// Register adds a ranking method, replacing any with the same name. It
+// must be called before any ranking is done, e.g. from an init function.
+func Register(m Method, fn Func) {
+	methods[m] = fn
+}
+
+// Methods returns the names of the available ranking methods, sorted
+func Methods() []string {
+	names := make([]string, 0, len(methods))
+	for m := range methods {
+		names = append(names, string(m))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse returns the ranking method called name
+func Parse(name string) (Method, error) {
+	m := Method(strings.ToLower(strings.TrimSpace(name)))
+	if _, ok := methods[m]; !ok {
+		return "", fmt.Errorf("unknown ranking %q (available: %s)", name, strings.Join(Methods(), ", "))
+	}
+	return m, nil
+}
+
+// Confidence returns the confidence m assigns to s at time now
+func (m Method) Confidence(s *models.SubmissionWithVotes, now time.Time) float64 {
+	fn, ok := methods[m]
+	if !ok {
+		fn = methods[Default]
+	}
+	return fn(s.WeightedUpvotes, s.WeightedDownvotes, now.Sub(s.CreatedAt))
+}
+
+// Rank sets the Confidence of each submission and sorts them most confident
+// first, breaking ties by vote score and then by age, newest first
+func (m Method) Rank(submissions []models.SubmissionWithVotes, now time.Time) {
+	for i := range submissions {
+		submissions[i].Confidence = m.Confidence(&submissions[i], now)
+	}
+
+	sort.SliceStable(submissions, func(i, j int) bool {
+		a, b := submissions[i], submissions[j]
+		if a.Confidence != b.Confidence {
+			return a.Confidence > b.Confidence
+		}
+		if a.VoteScore != b.VoteScore {
+			return a.VoteScore > b.VoteScore
+		}
+		return a.CreatedAt.After(b.CreatedAt)
+	})
+}
+
+// wilsonLowerBound returns the lower bound of the Wilson score interval for
+// the share of upvotes, or 0 without upvotes. That bound is exactly 0, but
+// computing it can leave rounding noise that would break ties.
+func wilsonLowerBound(up, down float64) float64 {
+	n := up + down
+	if up == 0 || n == 0 {
+		return 0
+	}
+
+	p := up / n
+	z2 := wilsonZ * wilsonZ
+	centre := p + z2/(2*n)
+	margin := wilsonZ * math.Sqrt((p*(1-p)+z2/(4*n))/n)
+	return (centre - margin) / (1 + z2/n)
+}
@@ -0,0 +1,175 @@
+package ranking
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/quentinsteinke/mkvmender/internal/models"
+)
+
+// approx reports whether a and b are equal to within rounding
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestScorers(t *testing.T) {
+	tests := []struct {
+		method   Method
+		up, down float64
+		age      time.Duration
+		want     float64
+	}{
+		// No votes: no evidence either way
+		{Wilson, 0, 0, 0, 0},
+		{Bayesian, 0, 0, 0, 0.5},
+		{Decay, 0, 0, 0, 0},
+
+		// Only downvotes, exactly zero so ties hold
+		{Wilson, 0, 1, 0, 0},
+		{Wilson, 0, 10, 0, 0},
+		{Bayesian, 0, 10, 0, 2.0 / 14},
+		{Decay, 0, 10, 0, 0},
+
+		// One upvote is weak evidence
+		{Wilson, 1, 0, 0, 0.20654329147389294},
+		{Bayesian, 1, 0, 0, 3.0 / 5},
+
+		{Wilson, 5, 5, 0, 0.2365895936154873},
+		{Bayesian, 5, 5, 0, 0.5},
+		{Bayesian, 1.5, 0, 0, 3.5 / 5.5},
+
+		// Decay halves Wilson every HalfLife and ignores negative ages
+		{Decay, 1, 0, 0, 0.20654329147389294},
+		{Decay, 1, 0, HalfLife, 0.20654329147389294 / 2},
+		{Decay, 1, 0, 2 * HalfLife, 0.20654329147389294 / 4},
+		{Decay, 1, 0, -HalfLife, 0.20654329147389294},
+		{Wilson, 1, 0, HalfLife, 0.20654329147389294},
+	}
+
+	for _, tt := range tests {
+		got := methods[tt.method](tt.up, tt.down, tt.age)
+		if !approx(got, tt.want) || (tt.want == 0 && got != 0) {
+			t.Errorf("%s(%v up, %v down, %v) = %v, want %v", tt.method, tt.up, tt.down, tt.age, got, tt.want)
+		}
+	}
+}
+
+func TestScorersStayInRange(t *testing.T) {
+	for _, m := range []Method{Wilson, Bayesian, Decay} {
+		for up := 0.0; up <= 50; up += 2.5 {
+			for down := 0.0; down <= 50; down += 2.5 {
+				got := methods[m](up, down, 30*24*time.Hour)
+				if got < -1e-9 || got > 1 || math.IsNaN(got) {
+					t.Errorf("%s(%v, %v) = %v, outside [0, 1]", m, up, down, got)
+				}
+			}
+		}
+	}
+}
+
+func TestScorersRewardEvidence(t *testing.T) {
+	for _, m := range []Method{Wilson, Bayesian, Decay} {
+		score := methods[m]
+
+		// A long positive record beats a couple of upvotes
+		if few, many := score(2, 0, 0), score(50, 2, 0); many <= few {
+			t.Errorf("%s: 50 up 2 down (%v) does not beat 2 up (%v)", m, many, few)
+		}
+		// More upvotes never lower the score, more downvotes never raise it
+		for n := 0.0; n < 20; n++ {
+			if score(n+1, 3, 0) < score(n, 3, 0) {
+				t.Errorf("%s: upvote %v lowered the score", m, n+1)
+			}
+			if score(3, n+1, 0) > score(3, n, 0) {
+				t.Errorf("%s: downvote %v raised the score", m, n+1)
+			}
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	sub := func(id int64, up, down int, age time.Duration) models.SubmissionWithVotes {
+		return models.SubmissionWithVotes{
+			ID:                id,
+			Upvotes:           up,
+			Downvotes:         down,
+			VoteScore:         up - down,
+			WeightedUpvotes:   float64(up),
+			WeightedDownvotes: float64(down),
+			CreatedAt:         now.Add(-age),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		method      Method
+		submissions []models.SubmissionWithVotes
+		want        []int64
+	}{
+		{
+			"record beats a lucky start",
+			Wilson,
+			[]models.SubmissionWithVotes{sub(1, 2, 0, time.Hour), sub(2, 40, 5, time.Hour)},
+			[]int64{2, 1},
+		},
+		{
+			"all zero votes: newest first",
+			Wilson,
+			[]models.SubmissionWithVotes{sub(1, 0, 0, 3*time.Hour), sub(2, 0, 0, time.Hour), sub(3, 0, 0, 2*time.Hour)},
+			[]int64{2, 3, 1},
+		},
+		{
+			"all downvotes tie at zero: higher score first",
+			Wilson,
+			[]models.SubmissionWithVotes{sub(1, 0, 9, time.Hour), sub(2, 0, 1, time.Hour), sub(3, 0, 0, time.Hour)},
+			[]int64{3, 2, 1},
+		},
+		{
+			"full ties keep their order",
+			Bayesian,
+			[]models.SubmissionWithVotes{sub(4, 3, 1, time.Hour), sub(2, 3, 1, time.Hour), sub(7, 3, 1, time.Hour)},
+			[]int64{4, 2, 7},
+		},
+		{
+			"decay favours recent names",
+			Decay,
+			[]models.SubmissionWithVotes{sub(1, 20, 0, 3*HalfLife), sub(2, 10, 0, 0)},
+			[]int64{2, 1},
+		},
+		{
+			"unknown method ranks like the default",
+			Method("nope"),
+			[]models.SubmissionWithVotes{sub(1, 2, 0, time.Hour), sub(2, 40, 5, time.Hour)},
+			[]int64{2, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		tt.method.Rank(tt.submissions, now)
+		// Ranking a ranked list again must not reorder it
+		tt.method.Rank(tt.submissions, now)
+
+		for i, s := range tt.submissions {
+			if s.ID != tt.want[i] {
+				t.Errorf("%s: position %d is %d, want order %v", tt.name, i, s.ID, tt.want)
+				break
+			}
+			if i > 0 && s.Confidence > tt.submissions[i-1].Confidence {
+				t.Errorf("%s: confidence rises at position %d", tt.name, i)
+			}
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, name := range []string{"wilson", "Bayesian", " DECAY "} {
+		if _, err := Parse(name); err != nil {
+			t.Errorf("Parse(%q): %v", name, err)
+		}
+	}
+	if _, err := Parse("votes"); err == nil {
+		t.Error("Parse accepted an unknown method")
+	}
+}
@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// Config holds rate limiting configuration. It is not decoded from YAML;
+// the server builds it from its config file's rate_limits section.
+type Config struct {
+	// TrustedProxies lists proxy IPs or CIDRs whose X-Forwarded-For header is
+	// believed when determining the client IP
+	TrustedProxies []string
+
+	// Routes maps a route pattern to its limit; routes without an entry are
+	// not limited
+	Routes map[string]Limit
+}
+
+// DefaultConfig returns default rate limits
+func DefaultConfig() Config {
+	return Config{
+		Routes: map[string]Limit{
+			"/api/register":            Per(5, time.Hour),
+			"/api/register/challenge":  Per(30, time.Hour),
+			"/api/lookup":              Per(600, time.Minute),
+			"/api/search":              Per(120, time.Minute),
+			"/api/upload":              Per(60, time.Hour),
+			"/api/vote":                Per(120, time.Hour),
+			"/api/vote/delete":         Per(120, time.Hour),
+			"/api/keys":                Per(60, time.Hour),
+			"/api/me":                  Per(10, time.Hour),
+			"/api/submissions":         Per(120, time.Hour),
+			"/api/submissions/history": Per(120, time.Minute),
+			"/api/submissions/reports": Per(30, time.Hour),
+		},
+	}
+}
+
+// Set holds one limiter per configured route
+type Set struct {
+	limiters       map[string]*Limiter
+	trustedProxies []netip.Prefix
+}
+
+// NewSet creates limiters for every enabled route in cfg
+func NewSet(cfg Config) (*Set, error) {
+	trusted, err := ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &Set{
+		limiters:       make(map[string]*Limiter),
+		trustedProxies: trusted,
+	}
+	for route, limit := range cfg.Routes {
+		if limit.Enabled() {
+			set.limiters[route] = New(limit)
+		}
+	}
+
+	return set, nil
+}
+
+// For returns the limiter for route, or nil if the route is not limited
+func (s *Set) For(route string) *Limiter {
+	if s == nil {
+		return nil
+	}
+	return s.limiters[route]
+}
+
+// ClientIP returns the client IP for r, honoring X-Forwarded-For only when
+// the request arrived through a trusted proxy
+func (s *Set) ClientIP(r *http.Request) string {
+	var trusted []netip.Prefix
+	if s != nil {
+		trusted = s.trustedProxies
+	}
+	return ClientIP(r, trusted)
+}
+
+// ParseTrustedProxies parses a list of IP addresses or CIDR ranges
+func ParseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, proxy := range proxies {
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// ClientIP returns the client IP for r. X-Forwarded-For entries are walked
+// from the right, skipping trusted proxies, so a client cannot spoof its
+// address by sending its own header.
+func ClientIP(r *http.Request, trusted []netip.Prefix) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+
+	addr, err := netip.ParseAddr(remote)
+	if err != nil {
+		return remote
+	}
+	remote = addr.Unmap().String()
+	if !isTrusted(addr, trusted) {
+		return remote
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		hopAddr, err := netip.ParseAddr(hop)
+		if err != nil {
+			// Malformed entry: stop at the last address we could verify
+			break
+		}
+		remote = hopAddr.Unmap().String()
+		if !isTrusted(hopAddr, trusted) {
+			break
+		}
+	}
+
+	return remote
+}
+
+// isTrusted reports whether addr falls within any trusted prefix
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
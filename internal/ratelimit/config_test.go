@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	prefixes, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1", "::ffff:172.16.0.1", "10.1.2.3/16"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies: %v", err)
+	}
+
+	want := []string{"10.0.0.0/8", "192.168.1.1/32", "172.16.0.1/32", "10.1.0.0/16"}
+	if len(prefixes) != len(want) {
+		t.Fatalf("got %d prefixes, want %d", len(prefixes), len(want))
+	}
+	for i, prefix := range prefixes {
+		if prefix.String() != want[i] {
+			t.Errorf("prefix %d = %s, want %s", i, prefix, want[i])
+		}
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "not-an-ip", "10.0.0", ""} {
+		if _, err := ParseTrustedProxies([]string{bad}); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{"no header", "203.0.113.5:1234", nil, "203.0.113.5"},
+		{"untrusted peer spoofing", "203.0.113.5:1234", []string{"1.2.3.4"}, "203.0.113.5"},
+		{"trusted proxy", "10.0.0.1:1234", []string{"1.2.3.4"}, "1.2.3.4"},
+		{"client prepends a spoofed hop", "10.0.0.1:1234", []string{"6.6.6.6, 1.2.3.4"}, "1.2.3.4"},
+		{"chained trusted proxies", "10.0.0.1:1234", []string{"1.2.3.4, 192.168.1.1, 10.0.0.2"}, "1.2.3.4"},
+		{"chain split across headers", "10.0.0.1:1234", []string{"6.6.6.6, 1.2.3.4", "10.0.0.2"}, "1.2.3.4"},
+		{"only trusted hops", "10.0.0.1:1234", []string{"10.0.0.3"}, "10.0.0.3"},
+		{"trusted proxy without header", "10.0.0.1:1234", nil, "10.0.0.1"},
+		{"malformed last hop", "10.0.0.1:1234", []string{"1.2.3.4, garbage"}, "10.0.0.1"},
+		{"malformed hop behind the client", "10.0.0.1:1234", []string{"garbage, 1.2.3.4"}, "1.2.3.4"},
+		{"hop with port", "10.0.0.1:1234", []string{"1.2.3.4:5678"}, "10.0.0.1"},
+		{"empty hops", "10.0.0.1:1234", []string{" , 1.2.3.4,, "}, "1.2.3.4"},
+		{"IPv4-mapped peer", "[::ffff:10.0.0.1]:1234", []string{"1.2.3.4"}, "1.2.3.4"},
+		{"IPv4-mapped hop", "10.0.0.1:1234", []string{"::ffff:1.2.3.4"}, "1.2.3.4"},
+		{"IPv4-mapped trusted hop", "10.0.0.1:1234", []string{"1.2.3.4, ::ffff:10.0.0.5"}, "1.2.3.4"},
+		{"IPv4-mapped untrusted peer", "[::ffff:203.0.113.5]:1234", []string{"1.2.3.4"}, "203.0.113.5"},
+		{"IPv6 peer", "[2001:db8::1]:1234", []string{"1.2.3.4"}, "2001:db8::1"},
+		{"remote without port", "203.0.113.5", nil, "203.0.113.5"},
+		{"unparsable remote", "@pipe", []string{"1.2.3.4"}, "@pipe"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/lookup", nil)
+		r.RemoteAddr = tt.remote
+		for _, v := range tt.xff {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		if got := ClientIP(r, trusted); got != tt.want {
+			t.Errorf("%s: ClientIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClientIPWithoutTrustedProxies(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/lookup", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+	if got := ClientIP(r, nil); got != "10.0.0.1" {
+		t.Errorf("ClientIP = %q, want 10.0.0.1", got)
+	}
+	var set *Set
+	if got := set.ClientIP(r); got != "10.0.0.1" {
+		t.Errorf("nil Set ClientIP = %q, want 10.0.0.1", got)
+	}
+}
+
+func TestNewSet(t *testing.T) {
+	set, err := NewSet(Config{
+		TrustedProxies: []string{"10.0.0.1"},
+		Routes: map[string]Limit{
+			"/api/vote":   Per(10, time.Minute),
+			"/api/search": {},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewSet: %v", err)
+	}
+
+	if set.For("/api/vote") == nil {
+		t.Error("limited route has no limiter")
+	}
+	if set.For("/api/search") != nil {
+		t.Error("disabled route has a limiter")
+	}
+	if set.For("/api/lookup") != nil {
+		t.Error("unconfigured route has a limiter")
+	}
+	var nilSet *Set
+	if nilSet.For("/api/vote") != nil {
+		t.Error("nil Set has a limiter")
+	}
+
+	if _, err := NewSet(Config{TrustedProxies: []string{"bogus"}}); err == nil {
+		t.Error("NewSet accepted an invalid trusted proxy")
+	}
+}
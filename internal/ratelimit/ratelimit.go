@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval controls how often idle buckets are discarded
+const sweepInterval = time.Minute
+
+// Limit describes a token bucket that refills at Rate tokens per second up to
+// a maximum of Burst tokens
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Per returns a Limit allowing n requests per period, all of which may be
+// used at once
+func Per(n int, period time.Duration) Limit {
+	return Limit{
+		Rate:  float64(n) / period.Seconds(),
+		Burst: n,
+	}
+}
+
+// Enabled reports whether the limit restricts anything
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Result describes the outcome of a rate limit check
+type Result struct {
+	Allowed    bool
+	Limit      int           // bucket capacity
+	Remaining  int           // whole tokens left after this request
+	Reset      time.Duration // time until the bucket is full again
+	RetryAfter time.Duration // time until the next token, if not allowed
+}
+
+// bucket holds the token state for a single key
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter applies a token-bucket limit independently per key
+type Limiter struct {
+	limit Limit
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// New creates a limiter enforcing limit for every key
+func New(limit Limit) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Limit returns the limit enforced by the limiter
+func (l *Limiter) Limit() Limit {
+	return l.limit
+}
+
+// Allow consumes a token for key if one is available
+func (l *Limiter) Allow(key string) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	burst := float64(l.limit.Burst)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+
+	// Refill tokens for the time elapsed since the last request
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*l.limit.Rate)
+		b.last = now
+	}
+
+	result := Result{Limit: l.limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.durationFor(1 - b.tokens)
+	}
+
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = l.durationFor(burst - b.tokens)
+
+	return result
+}
+
+// durationFor returns how long it takes to refill the given number of tokens
+func (l *Limiter) durationFor(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / l.limit.Rate * float64(time.Second))
+}
+
+// sweep discards buckets that have been idle long enough to refill completely
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	burst := float64(l.limit.Burst)
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.limit.Rate >= burst {
+			delete(l.buckets, key)
+		}
+	}
+}
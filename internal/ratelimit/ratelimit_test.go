@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a controllable time source for limiters
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// newTestLimiter returns a limiter on a fake clock
+func newTestLimiter(limit Limit) (*Limiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := New(limit)
+	l.now = clock.now
+	return l, clock
+}
+
+func TestPer(t *testing.T) {
+	limit := Per(120, time.Hour)
+	if limit.Burst != 120 {
+		t.Errorf("Burst = %d, want 120", limit.Burst)
+	}
+	if want := 120.0 / 3600; limit.Rate != want {
+		t.Errorf("Rate = %v, want %v", limit.Rate, want)
+	}
+
+	tests := []struct {
+		limit Limit
+		want  bool
+	}{
+		{Per(1, time.Minute), true},
+		{Per(0, time.Minute), false},
+		{Limit{Rate: 1}, false},
+		{Limit{Burst: 1}, false},
+		{Limit{}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.limit.Enabled(); got != tt.want {
+			t.Errorf("%+v.Enabled() = %v, want %v", tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestAllowBurstAndRefill(t *testing.T) {
+	// One token per second, up to three
+	l, clock := newTestLimiter(Per(3, 3*time.Second))
+
+	tests := []struct {
+		advance    time.Duration
+		allowed    bool
+		remaining  int
+		reset      time.Duration
+		retryAfter time.Duration
+	}{
+		{0, true, 2, time.Second, 0},
+		{0, true, 1, 2 * time.Second, 0},
+		{0, true, 0, 3 * time.Second, 0},
+		{0, false, 0, 3 * time.Second, time.Second},
+		{500 * time.Millisecond, false, 0, 2500 * time.Millisecond, 500 * time.Millisecond},
+		{500 * time.Millisecond, true, 0, 3 * time.Second, 0},
+		{2 * time.Second, true, 1, 2 * time.Second, 0},
+		// Refilling stops at the burst
+		{time.Hour, true, 2, time.Second, 0},
+	}
+
+	for i, tt := range tests {
+		clock.advance(tt.advance)
+		got := l.Allow("key")
+		if got.Allowed != tt.allowed || got.Remaining != tt.remaining || got.Reset != tt.reset || got.RetryAfter != tt.retryAfter {
+			t.Errorf("request %d: got %+v, want allowed %v remaining %d reset %v retry after %v",
+				i, got, tt.allowed, tt.remaining, tt.reset, tt.retryAfter)
+		}
+		if got.Limit != 3 {
+			t.Errorf("request %d: Limit = %d, want 3", i, got.Limit)
+		}
+	}
+}
+
+func TestAllowKeysAreIndependent(t *testing.T) {
+	l, _ := newTestLimiter(Per(1, time.Minute))
+
+	if !l.Allow("a").Allowed {
+		t.Fatal("first request for a was denied")
+	}
+	if l.Allow("a").Allowed {
+		t.Error("second request for a was allowed")
+	}
+	if !l.Allow("b").Allowed {
+		t.Error("first request for b was denied")
+	}
+}
+
+func TestSweepDiscardsFullBuckets(t *testing.T) {
+	// One token per 40 seconds, up to two
+	l, clock := newTestLimiter(Per(2, 80*time.Second))
+
+	l.Allow("idle")
+	l.Allow("busy")
+	l.Allow("busy")
+
+	// After 70 seconds idle, one token short, has refilled completely but
+	// busy, emptied, has not
+	clock.advance(sweepInterval + 10*time.Second)
+	l.Allow("other")
+
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("full bucket was not swept")
+	}
+	if _, ok := l.buckets["busy"]; !ok {
+		t.Error("partly refilled bucket was swept")
+	}
+
+	// A swept key starts over with a full bucket
+	if got := l.Allow("idle"); !got.Allowed || got.Remaining != 1 {
+		t.Errorf("swept key: got %+v, want allowed with 1 remaining", got)
+	}
+}
+
+func TestSweepRunsAtMostOncePerInterval(t *testing.T) {
+	l, clock := newTestLimiter(Per(2, time.Second))
+
+	l.Allow("a")
+	clock.advance(sweepInterval / 2)
+	l.Allow("b")
+
+	// a refilled long ago, but the last sweep was less than an interval ago
+	if _, ok := l.buckets["a"]; !ok {
+		t.Error("bucket was swept before the interval passed")
+	}
+}
@@ -0,0 +1,182 @@
+// Package username validates usernames and derives the canonical form used
+// to keep them unique regardless of case and look-alike characters.
+package username
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MinLength is the minimum username length in characters
+	MinLength = 3
+	// MaxLength is the maximum username length in characters
+	MaxLength = 32
+)
+
+// reservedNames cannot be registered because they could be mistaken for the
+// service or its staff. Names that look like them are rejected too.
+var reservedNames = []string{
+	"admin", "administrator", "root", "system", "moderator", "mod", "staff",
+	"support", "help", "api", "mkvmender", "anonymous", "deleted", "null",
+	"nobody",
+}
+
+// reserved holds the canonical forms of reservedNames
+var reserved = func() map[string]bool {
+	m := make(map[string]bool, len(reservedNames))
+	for _, name := range reservedNames {
+		m[Canonical(name)] = true
+	}
+	return m
+}()
+
+// confusables maps characters that render like a Latin letter or digit to
+// that letter, after case folding. It covers the Cyrillic and Greek
+// look-alikes most often used for impersonation and the digits that pass
+// for letters.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'һ': 'h', 'і': 'l',
+	'ӏ': 'l', 'ј': 'j', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p',
+	'ԛ': 'q', 'ѕ': 's', 'т': 't', 'ս': 'u', 'ѵ': 'v', 'ԝ': 'w', 'х': 'x',
+	'у': 'y', 'ү': 'y', 'ɡ': 'g',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'l', 'κ': 'k', 'ν': 'v',
+	'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ϲ': 'c',
+	// Latin letters and digits that look alike
+	'i': 'l', '1': 'l', '|': 'l', '0': 'o',
+}
+
+// separators may appear between other characters in a username
+const separators = "_-."
+
+// Validate checks that name is an acceptable new username: MinLength to
+// MaxLength letters, digits and single separators (_ - .), starting and
+// ending with a letter or digit, using a single alphabet and not reserved.
+// The error message is suitable for showing to the user.
+func Validate(name string) error {
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("username must be valid UTF-8")
+	}
+	name = norm.NFC.String(name)
+
+	length := utf8.RuneCountInString(name)
+	if length < MinLength || length > MaxLength {
+		return fmt.Errorf("username must be between %d and %d characters", MinLength, MaxLength)
+	}
+
+	var prev rune
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+		case strings.ContainsRune(separators, r):
+			if i == 0 || strings.ContainsRune(separators, prev) {
+				return fmt.Errorf("username must start with a letter or digit and not repeat _ - or .")
+			}
+		default:
+			return fmt.Errorf("username may only contain letters, digits, _ - and .")
+		}
+		prev = r
+	}
+	if strings.ContainsRune(separators, prev) {
+		return fmt.Errorf("username must end with a letter or digit")
+	}
+	if mixesScripts(name) {
+		return fmt.Errorf("username must not mix letters from different alphabets")
+	}
+
+	if IsReserved(name) {
+		return fmt.Errorf("username %q is reserved", name)
+	}
+
+	return nil
+}
+
+// IsReserved reports whether name, or a name that looks like it, is
+// reserved
+func IsReserved(name string) bool {
+	return reserved[Canonical(name)]
+}
+
+// Canonical returns the form of name used for uniqueness: compatibility
+// normalized, case folded, with look-alike characters mapped to a single
+// representative and separators removed. Two usernames with the same
+// canonical form are considered the same name, so "Bob", "B0B" and "b.o.b"
+// (or "bоb" with a Cyrillic о) cannot coexist.
+func Canonical(name string) string {
+	folded := cases.Fold().String(norm.NFKC.String(name))
+
+	var b strings.Builder
+	b.Grow(len(folded))
+	var base rune
+	for _, r := range norm.NFD.String(folded) {
+		if strings.ContainsRune(separators, r) {
+			continue
+		}
+		// Accents on European letters are easy to miss, so "josé" and
+		// "jose" are the same name; marks in other scripts change the
+		// letter and are kept
+		if unicode.Is(unicode.Mn, r) {
+			if isEuropean(base) {
+				continue
+			}
+		} else {
+			base = r
+		}
+		if mapped, ok := confusables[r]; ok {
+			r = mapped
+		}
+		b.WriteRune(r)
+	}
+
+	return norm.NFC.String(b.String())
+}
+
+// isEuropean reports whether r is a Latin, Greek or Cyrillic letter
+func isEuropean(r rune) bool {
+	return unicode.In(r, unicode.Latin, unicode.Greek, unicode.Cyrillic)
+}
+
+// mixesScripts reports whether name contains letters from more than one
+// script, as in "pаypal" with a Cyrillic а. Han, Hiragana, Katakana and
+// Hangul may be combined since Japanese and Korean names do so.
+func mixesScripts(name string) bool {
+	seen := ""
+	for _, r := range name {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		script := scriptOf(r)
+		if script == "" {
+			continue
+		}
+		if seen != "" && script != seen {
+			return true
+		}
+		seen = script
+	}
+	return false
+}
+
+// scriptOf returns the name of the script r belongs to, treating the East
+// Asian scripts as one, or "" for characters common to all scripts
+func scriptOf(r rune) string {
+	if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul, unicode.Bopomofo) {
+		return "CJK"
+	}
+	for name, table := range unicode.Scripts {
+		if name == "Common" || name == "Inherited" {
+			continue
+		}
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,147 @@
+package username
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		want string // substring of the error, "" for valid
+	}{
+		{"bob", ""},
+		{"Bob_Smith", ""},
+		{"b.o-b_1", ""},
+		{"ééé", ""},
+		{strings.Repeat("a", MaxLength), ""},
+		{strings.Repeat("я", MaxLength), ""},
+		{"Москва", ""},
+		{"山田たろう", ""},
+		{"김철수", ""},
+
+		// Length is counted in characters
+		{"ab", "between"},
+		{"", "between"},
+		{strings.Repeat("a", MaxLength+1), "between"},
+		{strings.Repeat("я", MaxLength+1), "between"},
+		{"\xff\xfe\xfd", "UTF-8"},
+
+		{"_bob", "start with"},
+		{"bo__b", "not repeat"},
+		{"bo_-b", "not repeat"},
+		{"bob.", "end with"},
+		{"bob smith", "only contain"},
+		{"bob@example", "only contain"},
+		{"[deleted]", "only contain"},
+
+		// Cyrillic а among Latin letters
+		{"pаypal", "different alphabets"},
+		{"αβγabc", "different alphabets"},
+		{"bobМосква", "different alphabets"},
+
+		{"admin", "reserved"},
+		{"Admin", "reserved"},
+		{"ADM1N", "reserved"},
+		{"a.d.m.i.n", "reserved"},
+		{"ａｄｍｉｎ", "reserved"},
+		{"m0d", "reserved"},
+		{"deleted", "reserved"},
+		{"Dеlеtеd", "different alphabets"},
+		{"mkvmender", "reserved"},
+	}
+
+	for _, tt := range tests {
+		err := Validate(tt.name)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("Validate(%q) = %v, want nil", tt.name, err)
+		case tt.want != "" && err == nil:
+			t.Errorf("Validate(%q) = nil, want error containing %q", tt.name, tt.want)
+		case tt.want != "" && !strings.Contains(err.Error(), tt.want):
+			t.Errorf("Validate(%q) = %v, want error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	same := [][]string{
+		{"bob", "Bob", "BOB", "B0B", "b.o.b", "b_o-b", "bоb"},
+		// Cyrillic а and р
+		{"paypal", "pаypal", "раураl"},
+		// l, 1, i and | all look alike
+		{"bill", "b1ll", "BILL", "bi11", "b|ll"},
+		{"jose", "José", "JOSÉ", "josé"},
+		{"admin", "ａｄｍｉｎ", "αdmin"},
+		{"straße", "STRASSE", "strasse"},
+	}
+	for _, group := range same {
+		want := Canonical(group[0])
+		for _, name := range group[1:] {
+			if got := Canonical(name); got != want {
+				t.Errorf("Canonical(%q) = %q, want %q as for %q", name, got, want, group[0])
+			}
+		}
+	}
+
+	different := [][2]string{
+		{"bob", "rob"},
+		{"ana", "anna"},
+		{"bob", "bobb"},
+		// Marks outside European scripts change the letter
+		{"क", "क़"},
+	}
+	for _, pair := range different {
+		if Canonical(pair[0]) == Canonical(pair[1]) {
+			t.Errorf("Canonical(%q) == Canonical(%q) = %q", pair[0], pair[1], Canonical(pair[0]))
+		}
+	}
+}
+
+func TestIsReserved(t *testing.T) {
+	for _, name := range reservedNames {
+		if !IsReserved(name) {
+			t.Errorf("IsReserved(%q) = false", name)
+		}
+	}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"Root", true},
+		{"r00t", true},
+		{"n.u.l.l", true},
+		{"rooted", false},
+		{"admins", false},
+		{"alice", false},
+	}
+	for _, tt := range tests {
+		if got := IsReserved(tt.name); got != tt.want {
+			t.Errorf("IsReserved(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMixesScripts(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"alice", false},
+		{"alice123", false},
+		{"ΑΘΗΝΑ", false},
+		{"漢字かなカナ", false},
+		{"한국어漢字", false},
+		{"аlice", true},
+		{"alicе", true},
+		{"alice漢字", true},
+		{"αlice", true},
+	}
+
+	for _, tt := range tests {
+		if got := mixesScripts(tt.name); got != tt.want {
+			t.Errorf("mixesScripts(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
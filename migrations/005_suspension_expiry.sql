@@ -0,0 +1,9 @@
+-- MKV Mender Suspension Expiry Migration
+
+-- End of a temporary suspension; NULL means permanent (or not suspended)
+ALTER TABLE users ADD COLUMN suspended_until DATETIME;
+
+-- When a temporary moderation action such as a suspension ends
+ALTER TABLE moderation_actions ADD COLUMN expires_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_users_suspended_until ON users(suspended_until);
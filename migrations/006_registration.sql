@@ -0,0 +1,22 @@
+-- MKV Mender Registration Migration
+
+-- Case-folded, look-alike-normalized username used to enforce uniqueness.
+-- Filled in for existing users at startup; NULL for legacy collisions.
+ALTER TABLE users ADD COLUMN username_canonical TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_canonical ON users(username_canonical);
+
+-- Invite codes for servers that gate registration
+CREATE TABLE IF NOT EXISTS invite_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code TEXT UNIQUE NOT NULL,
+    created_by INTEGER NOT NULL,
+    max_uses INTEGER NOT NULL DEFAULT 1,
+    uses INTEGER NOT NULL DEFAULT 0,
+    expires_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Which invite, if any, a user registered with
+ALTER TABLE users ADD COLUMN invite_code_id INTEGER REFERENCES invite_codes(id) ON DELETE SET NULL;
@@ -0,0 +1,27 @@
+-- MKV Mender Moderation Action Types Migration
+
+-- Drop the CHECK constraints on action_type and target_type so new kinds of
+-- moderation actions can be recorded; the application validates them.
+-- SQLite cannot drop a constraint, so the table is rebuilt.
+CREATE TABLE moderation_actions_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    admin_id INTEGER NOT NULL,
+    action_type TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id INTEGER NOT NULL,
+    reason TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME,
+    FOREIGN KEY (admin_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO moderation_actions_new (id, admin_id, action_type, target_type, target_id, reason, created_at, expires_at)
+SELECT id, admin_id, action_type, target_type, target_id, reason, created_at, expires_at
+FROM moderation_actions;
+
+DROP TABLE moderation_actions;
+ALTER TABLE moderation_actions_new RENAME TO moderation_actions;
+
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_admin_id ON moderation_actions(admin_id);
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_target ON moderation_actions(target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_created_at ON moderation_actions(created_at DESC);
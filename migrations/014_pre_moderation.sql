@@ -0,0 +1,37 @@
+-- MKV Mender Pre-Moderation Migration
+
+-- Submissions from users below the configured trust level wait for a
+-- moderator's approval before they appear in lookups and searches
+ALTER TABLE naming_submissions ADD COLUMN pending BOOLEAN NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_naming_submissions_pending ON naming_submissions(pending);
+
+-- Expose whether a submission is awaiting approval
+DROP VIEW IF EXISTS submissions_with_votes;
+
+CREATE VIEW submissions_with_votes AS
+SELECT
+    ns.id,
+    ns.hash_id,
+    ns.user_id,
+    ns.filename,
+    ns.created_at,
+    ns.hidden,
+    ns.pending,
+    fh.hash,
+    fh.file_size,
+    fh.media_type,
+    u.username,
+    u.reputation,
+    u.trust_level,
+    COALESCE(SUM(v.vote_type), 0) as vote_score,
+    COUNT(CASE WHEN v.vote_type = 1 THEN 1 END) as upvotes,
+    COUNT(CASE WHEN v.vote_type = -1 THEN 1 END) as downvotes,
+    COALESCE(SUM(CASE WHEN v.vote_type = 1 THEN v.weight END), 0) as weighted_upvotes,
+    COALESCE(SUM(CASE WHEN v.vote_type = -1 THEN v.weight END), 0) as weighted_downvotes
+FROM naming_submissions ns
+JOIN file_hashes fh ON ns.hash_id = fh.id
+JOIN users u ON ns.user_id = u.id
+LEFT JOIN votes v ON ns.id = v.submission_id AND v.neutralized = FALSE
+GROUP BY ns.id, ns.hash_id, ns.user_id, ns.filename, ns.created_at, ns.hidden, ns.pending,
+         fh.hash, fh.file_size, fh.media_type, u.username, u.reputation, u.trust_level;
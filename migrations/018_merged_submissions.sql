@@ -0,0 +1,5 @@
+-- MKV Mender Merged Submissions Migration
+
+-- Records which submission a merged duplicate was folded into. Its votes
+-- live on there, so it cannot be restored on its own
+ALTER TABLE naming_submissions ADD COLUMN merged_into INTEGER REFERENCES naming_submissions(id) ON DELETE SET NULL;
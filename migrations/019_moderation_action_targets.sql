@@ -0,0 +1,13 @@
+-- MKV Mender Moderation Action Targets Migration
+
+-- Bulk actions are logged once for the whole operation; each submission
+-- they changed is linked here so its timeline shows the action too
+CREATE TABLE IF NOT EXISTS moderation_action_targets (
+    action_id INTEGER NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id INTEGER NOT NULL,
+    PRIMARY KEY (action_id, target_type, target_id),
+    FOREIGN KEY (action_id) REFERENCES moderation_actions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_action_targets_target ON moderation_action_targets(target_type, target_id);
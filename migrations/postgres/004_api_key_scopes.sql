@@ -0,0 +1,5 @@
+-- MKV Mender API Key Scopes Migration (PostgreSQL)
+
+-- Comma-separated scopes (read, upload, vote, admin). Existing keys keep the
+-- full access they had before scopes existed.
+ALTER TABLE api_keys ADD COLUMN scopes TEXT NOT NULL DEFAULT 'read,upload,vote,admin';
@@ -0,0 +1,9 @@
+-- MKV Mender Suspension Expiry Migration (PostgreSQL)
+
+-- End of a temporary suspension; NULL means permanent (or not suspended)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_until TIMESTAMP;
+
+-- When a temporary moderation action such as a suspension ends
+ALTER TABLE moderation_actions ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_users_suspended_until ON users(suspended_until);
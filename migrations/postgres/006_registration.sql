@@ -0,0 +1,22 @@
+-- MKV Mender Registration Migration (PostgreSQL)
+
+-- Case-folded, look-alike-normalized username used to enforce uniqueness.
+-- Filled in for existing users at startup; NULL for legacy collisions.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username_canonical TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_canonical ON users(username_canonical);
+
+-- Invite codes for servers that gate registration
+CREATE TABLE IF NOT EXISTS invite_codes (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    code TEXT UNIQUE NOT NULL,
+    created_by BIGINT NOT NULL,
+    max_uses INTEGER NOT NULL DEFAULT 1,
+    uses INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Which invite, if any, a user registered with
+ALTER TABLE users ADD COLUMN IF NOT EXISTS invite_code_id BIGINT REFERENCES invite_codes(id) ON DELETE SET NULL;
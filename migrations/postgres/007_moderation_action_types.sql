@@ -0,0 +1,6 @@
+-- MKV Mender Moderation Action Types Migration (PostgreSQL)
+
+-- Drop the CHECK constraints on action_type and target_type so new kinds of
+-- moderation actions can be recorded; the application validates them.
+ALTER TABLE moderation_actions DROP CONSTRAINT IF EXISTS moderation_actions_action_type_check;
+ALTER TABLE moderation_actions DROP CONSTRAINT IF EXISTS moderation_actions_target_type_check;
@@ -0,0 +1,23 @@
+-- MKV Mender Submission Revisions Migration (PostgreSQL)
+
+-- Snapshots of a submission's filename and metadata. A row is written for
+-- the original upload and for every later change, with who made it and why.
+CREATE TABLE IF NOT EXISTS submission_revisions (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    submission_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    filename TEXT NOT NULL,
+    title TEXT,
+    year INTEGER,
+    season INTEGER,
+    episode INTEGER,
+    quality TEXT,
+    source TEXT,
+    reason TEXT,
+    votes_reset BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (submission_id) REFERENCES naming_submissions(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_submission_revisions_submission_id ON submission_revisions(submission_id);
@@ -0,0 +1,12 @@
+-- MKV Mender Submission Revisions Backfill Migration (PostgreSQL)
+
+-- Record the current state of submissions created before revisions were
+-- tracked as their original revision, so every submission has a history.
+INSERT INTO submission_revisions
+    (submission_id, user_id, filename, title, year, season, episode, quality, source, created_at)
+SELECT ns.id, ns.user_id, ns.filename, nm.title, nm.year, nm.season, nm.episode, nm.quality, nm.source, ns.created_at
+FROM naming_submissions ns
+LEFT JOIN naming_metadata nm ON nm.submission_id = ns.id
+WHERE NOT EXISTS (
+    SELECT 1 FROM submission_revisions sr WHERE sr.submission_id = ns.id
+);
@@ -0,0 +1,38 @@
+-- MKV Mender Reputation Migration (PostgreSQL)
+
+-- Reputation earned from votes on a user's submissions and agreement with
+-- consensus, and the trust level it unlocks (0 new, 1 member, 2 trusted,
+-- 3 veteran). Both are recalculated periodically by the server.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS reputation INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS trust_level INTEGER NOT NULL DEFAULT 0;
+
+-- How much a vote counts, from the voter's trust level
+ALTER TABLE votes ADD COLUMN IF NOT EXISTS weight DOUBLE PRECISION NOT NULL DEFAULT 1;
+
+-- Add trust-weighted vote counts and the uploader's reputation
+DROP VIEW IF EXISTS submissions_with_votes;
+
+CREATE VIEW submissions_with_votes AS
+SELECT
+    ns.id,
+    ns.hash_id,
+    ns.user_id,
+    ns.filename,
+    ns.created_at,
+    fh.hash,
+    fh.file_size,
+    fh.media_type,
+    u.username,
+    u.reputation,
+    u.trust_level,
+    COALESCE(SUM(v.vote_type), 0) as vote_score,
+    COUNT(CASE WHEN v.vote_type = 1 THEN 1 END) as upvotes,
+    COUNT(CASE WHEN v.vote_type = -1 THEN 1 END) as downvotes,
+    COALESCE(SUM(CASE WHEN v.vote_type = 1 THEN v.weight END), 0) as weighted_upvotes,
+    COALESCE(SUM(CASE WHEN v.vote_type = -1 THEN v.weight END), 0) as weighted_downvotes
+FROM naming_submissions ns
+JOIN file_hashes fh ON ns.hash_id = fh.id
+JOIN users u ON ns.user_id = u.id
+LEFT JOIN votes v ON ns.id = v.submission_id
+GROUP BY ns.id, ns.hash_id, ns.user_id, ns.filename, ns.created_at,
+         fh.hash, fh.file_size, fh.media_type, u.username, u.reputation, u.trust_level;
@@ -0,0 +1,55 @@
+-- MKV Mender Vote Manipulation Detection Migration (PostgreSQL)
+
+-- Votes excluded from scores and reputation because they were flagged as
+-- manipulation. They are kept so the decision can be reversed.
+ALTER TABLE votes ADD COLUMN IF NOT EXISTS neutralized BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Review queue of suspicious voting found by the detection job: a voter
+-- whose upvotes for target_user_id's submissions match a pattern
+CREATE TABLE IF NOT EXISTS vote_flags (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    voter_id BIGINT NOT NULL,
+    target_user_id BIGINT NOT NULL,
+    pattern TEXT NOT NULL,
+    details TEXT NOT NULL,
+    vote_count INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'open',
+    neutralized BOOLEAN NOT NULL DEFAULT FALSE,
+    reviewed_by BIGINT,
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(voter_id, target_user_id, pattern),
+    FOREIGN KEY (voter_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (target_user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (reviewed_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_vote_flags_status ON vote_flags(status);
+
+-- Leave neutralized votes out of the counts
+DROP VIEW IF EXISTS submissions_with_votes;
+
+CREATE VIEW submissions_with_votes AS
+SELECT
+    ns.id,
+    ns.hash_id,
+    ns.user_id,
+    ns.filename,
+    ns.created_at,
+    fh.hash,
+    fh.file_size,
+    fh.media_type,
+    u.username,
+    u.reputation,
+    u.trust_level,
+    COALESCE(SUM(v.vote_type), 0) as vote_score,
+    COUNT(CASE WHEN v.vote_type = 1 THEN 1 END) as upvotes,
+    COUNT(CASE WHEN v.vote_type = -1 THEN 1 END) as downvotes,
+    COALESCE(SUM(CASE WHEN v.vote_type = 1 THEN v.weight END), 0) as weighted_upvotes,
+    COALESCE(SUM(CASE WHEN v.vote_type = -1 THEN v.weight END), 0) as weighted_downvotes
+FROM naming_submissions ns
+JOIN file_hashes fh ON ns.hash_id = fh.id
+JOIN users u ON ns.user_id = u.id
+LEFT JOIN votes v ON ns.id = v.submission_id AND v.neutralized = FALSE
+GROUP BY ns.id, ns.hash_id, ns.user_id, ns.filename, ns.created_at,
+         fh.hash, fh.file_size, fh.media_type, u.username, u.reputation, u.trust_level;
@@ -0,0 +1,8 @@
+-- MKV Mender Self-Vote Removal Migration
+
+-- Users can no longer vote on their own submissions; drop the votes cast
+-- before that was enforced.
+DELETE FROM votes
+WHERE user_id = (
+    SELECT ns.user_id FROM naming_submissions ns WHERE ns.id = votes.submission_id
+);
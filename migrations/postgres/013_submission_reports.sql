@@ -0,0 +1,55 @@
+-- MKV Mender Submission Reports Migration (PostgreSQL)
+
+-- Submissions hidden from lookups and searches, e.g. after enough reports,
+-- until a moderator resolves them
+ALTER TABLE naming_submissions ADD COLUMN IF NOT EXISTS hidden BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Reports of bad submissions from the community, one per user and
+-- submission, queued for moderators
+CREATE TABLE IF NOT EXISTS submission_reports (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    submission_id BIGINT NOT NULL,
+    reporter_id BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    details TEXT,
+    status TEXT NOT NULL DEFAULT 'open',
+    resolved_by BIGINT,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(submission_id, reporter_id),
+    FOREIGN KEY (submission_id) REFERENCES naming_submissions(id) ON DELETE CASCADE,
+    FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (resolved_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_submission_reports_status ON submission_reports(status);
+CREATE INDEX IF NOT EXISTS idx_submission_reports_submission_id ON submission_reports(submission_id);
+
+-- Expose whether a submission is hidden
+DROP VIEW IF EXISTS submissions_with_votes;
+
+CREATE VIEW submissions_with_votes AS
+SELECT
+    ns.id,
+    ns.hash_id,
+    ns.user_id,
+    ns.filename,
+    ns.created_at,
+    ns.hidden,
+    fh.hash,
+    fh.file_size,
+    fh.media_type,
+    u.username,
+    u.reputation,
+    u.trust_level,
+    COALESCE(SUM(v.vote_type), 0) as vote_score,
+    COUNT(CASE WHEN v.vote_type = 1 THEN 1 END) as upvotes,
+    COUNT(CASE WHEN v.vote_type = -1 THEN 1 END) as downvotes,
+    COALESCE(SUM(CASE WHEN v.vote_type = 1 THEN v.weight END), 0) as weighted_upvotes,
+    COALESCE(SUM(CASE WHEN v.vote_type = -1 THEN v.weight END), 0) as weighted_downvotes
+FROM naming_submissions ns
+JOIN file_hashes fh ON ns.hash_id = fh.id
+JOIN users u ON ns.user_id = u.id
+LEFT JOIN votes v ON ns.id = v.submission_id AND v.neutralized = FALSE
+GROUP BY ns.id, ns.hash_id, ns.user_id, ns.filename, ns.created_at, ns.hidden,
+         fh.hash, fh.file_size, fh.media_type, u.username, u.reputation, u.trust_level;
@@ -0,0 +1,39 @@
+-- MKV Mender Soft Delete Migration (PostgreSQL)
+
+-- Deleted submissions are kept, with who deleted them, until the retention
+-- period passes so a moderator can restore them
+ALTER TABLE naming_submissions ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE naming_submissions ADD COLUMN IF NOT EXISTS deleted_by BIGINT REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_naming_submissions_deleted_at ON naming_submissions(deleted_at);
+
+-- Leave deleted submissions out of every query built on the view
+DROP VIEW IF EXISTS submissions_with_votes;
+
+CREATE VIEW submissions_with_votes AS
+SELECT
+    ns.id,
+    ns.hash_id,
+    ns.user_id,
+    ns.filename,
+    ns.created_at,
+    ns.hidden,
+    ns.pending,
+    fh.hash,
+    fh.file_size,
+    fh.media_type,
+    u.username,
+    u.reputation,
+    u.trust_level,
+    COALESCE(SUM(v.vote_type), 0) as vote_score,
+    COUNT(CASE WHEN v.vote_type = 1 THEN 1 END) as upvotes,
+    COUNT(CASE WHEN v.vote_type = -1 THEN 1 END) as downvotes,
+    COALESCE(SUM(CASE WHEN v.vote_type = 1 THEN v.weight END), 0) as weighted_upvotes,
+    COALESCE(SUM(CASE WHEN v.vote_type = -1 THEN v.weight END), 0) as weighted_downvotes
+FROM naming_submissions ns
+JOIN file_hashes fh ON ns.hash_id = fh.id
+JOIN users u ON ns.user_id = u.id
+LEFT JOIN votes v ON ns.id = v.submission_id AND v.neutralized = FALSE
+WHERE ns.deleted_at IS NULL
+GROUP BY ns.id, ns.hash_id, ns.user_id, ns.filename, ns.created_at, ns.hidden, ns.pending,
+         fh.hash, fh.file_size, fh.media_type, u.username, u.reputation, u.trust_level;
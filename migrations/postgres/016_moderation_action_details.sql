@@ -0,0 +1,5 @@
+-- MKV Mender Moderation Action Details Migration (PostgreSQL)
+
+-- Bulk moderation logs one action for the whole operation; details is a
+-- JSON object listing what it matched and changed
+ALTER TABLE moderation_actions ADD COLUMN IF NOT EXISTS details TEXT;
@@ -0,0 +1,8 @@
+-- MKV Mender Ghost Account Migration (PostgreSQL)
+
+-- Marks the account that keeps what deleted users leave behind, so it is
+-- never confused with a real account that happens to share its name
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_ghost BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- There is at most one ghost account
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_ghost ON users(is_ghost) WHERE is_ghost = TRUE;
@@ -0,0 +1,5 @@
+-- MKV Mender Merged Submissions Migration (PostgreSQL)
+
+-- Records which submission a merged duplicate was folded into. Its votes
+-- live on there, so it cannot be restored on its own
+ALTER TABLE naming_submissions ADD COLUMN IF NOT EXISTS merged_into BIGINT REFERENCES naming_submissions(id) ON DELETE SET NULL;